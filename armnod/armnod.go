@@ -55,10 +55,23 @@ const (
 // The *Chooser interfaces are required to consume a constant number of bytes
 // of guacamole on each call to Next.  This is done to assure deterministic
 // generation of strings for the more complex chooser arrangments.
+//
+// Version selects the algorithms used to turn seeds into strings, including the
+// guacamole stream beneath them, how runes are stretched over the charset, and
+// how chosen strings are spread across seeds.  The zero value means
+// guacamole.Version1, so a Configuration that does not set Version keeps
+// producing the same strings for the same seeds.  StringChoosers receive a
+// generator of this version, and the ZipfParams given to
+// ChooseFromFixedSetZipf must have the same version.  See guacamole.Version.
+//
+// The Version field was added after the first three; composite literals that
+// list the fields without their names, such as Configuration{Default, s, l},
+// no longer compile and must name them instead.
 type Configuration struct {
 	Charset
 	StringChooser
 	LengthChooser
+	Version guacamole.Version
 }
 
 // StringChooser uses the provided PRNG to specify the next string to generate.
@@ -77,7 +90,8 @@ type LengthChooser interface {
 	NextArmnodLength(*guacamole.Guacamole) uint64
 }
 
-// Generator builds a generator object from the provided configuration.
+// Generator builds a generator object from the provided configuration.  It
+// panics if the configuration's Version is not supported.
 func (c Configuration) Generator() *Generator {
 	g := &Generator{
 		configuration: c,
@@ -113,30 +127,27 @@ func (g *Generator) initialize() {
 	if g.configuration.LengthChooser == nil {
 		g.configuration.LengthChooser = &ConstantLengthChooser{10}
 	}
+	if g.configuration.Version == 0 {
+		g.configuration.Version = guacamole.Version1
+	}
 	if g.random == nil {
-		g.random = guacamole.New()
+		g.random = guacamole.NewVersion(g.configuration.Version)
 	}
 	if g.strings == nil {
-		g.strings = guacamole.New()
-	}
-	charset := []rune(string(g.configuration.Charset))
-	// TODO(rescrv): Consider moving this to C; it's about an order of magnitude
-	// slower in Go.  I assume this is because of bounds checks as unrolling the
-	// loop had a positive effect in the C code and has zero effect in Go,
-	// hinting that maybe there's some branching getting in the way.
-	for i := 0; i < runeStretchLength; i++ {
-		d := int(float64(i) / float64(runeStretchLength) * float64(len(charset)))
-		if d < 0 || d >= runeStretchLength {
-			panic("invariant violated")
-		}
-		g.runes[i] = charset[d]
+		g.strings = guacamole.NewVersion(g.configuration.Version)
 	}
+	stretchRunes(g.configuration.Version, &g.runes, []rune(string(g.configuration.Charset)))
 	length := g.configuration.LengthChooser.MaxArmnodLength()
 	g.bbuf = make([]byte, length)
 	g.rbuf = make([]rune, length)
 	g.Seed(0)
 }
 
+// Version returns the algorithm version of the generator.
+func (g *Generator) Version() guacamole.Version {
+	return g.configuration.Version
+}
+
 // Seed the random number generator with the provided constant.  Seed is not the
 // same as a typical random number generator because the integer distance
 // separating two seeds directly correlates with the number of random strings
@@ -197,6 +208,17 @@ func ChooseFromFixedSetZipf(params *guacamole.ZipfParams) StringChooser {
 	}
 }
 
+// ChooseFromFixedSetWeighted constructs a StringChooser that will repeatedly
+// select from a set of table.Len() random strings.  Strings will be chosen
+// indefinitely and string i will be chosen with the weight of outcome i in the
+// table.  The set of strings is the same set of strings ChooseFromFixedSet
+// would select from for N = table.Len().
+func ChooseFromFixedSetWeighted(table *guacamole.AliasTable) StringChooser {
+	return &fixedStringChooserWeighted{
+		table: table,
+	}
+}
+
 // InitializedFixedSet constructions a string chooser that returns every string
 // in a set of N random strings exactly once.  After all strings are returned,
 // the string chooser will stop generating strings.
@@ -259,8 +281,37 @@ const (
 // to that limit, the less even the representation of characters in the output
 // will be.
 
-func distribute(x, c uint64) uint64 {
-	return x * (math.MaxUint64 / c)
+// stretchRunes spreads charset over runes, as version v does, so that a byte
+// of guacamole indexes runes to pick a character.
+func stretchRunes(v guacamole.Version, runes *[runeStretchLength]rune, charset []rune) {
+	switch v {
+	case guacamole.Version1:
+		// TODO(rescrv): Consider moving this to C; it's about an order of
+		// magnitude slower in Go.  I assume this is because of bounds checks
+		// as unrolling the loop had a positive effect in the C code and has
+		// zero effect in Go, hinting that maybe there's some branching getting
+		// in the way.
+		for i := 0; i < runeStretchLength; i++ {
+			d := int(float64(i) / float64(runeStretchLength) * float64(len(charset)))
+			if d < 0 || d >= runeStretchLength {
+				panic("invariant violated")
+			}
+			runes[i] = charset[d]
+		}
+	default:
+		panic("armnod: unsupported algorithm version")
+	}
+}
+
+// distribute spreads x, one of c strings, across the 2^64 seeds, as version v
+// does.  The version is that of the generator passed to the StringChooser.
+func distribute(v guacamole.Version, x, c uint64) uint64 {
+	switch v {
+	case guacamole.Version1:
+		return x * (math.MaxUint64 / c)
+	default:
+		panic("armnod: unsupported algorithm version")
+	}
 }
 
 type fixedStringChooser struct {
@@ -268,7 +319,7 @@ type fixedStringChooser struct {
 }
 
 func (c *fixedStringChooser) NextArmnodString(g *guacamole.Guacamole) (uint64, bool) {
-	return distribute(uint64(float64(c.N)*g.Float64()), c.N), true
+	return distribute(g.Version(), uint64(float64(c.N)*g.Float64()), c.N), true
 }
 
 type fixedStringChooserZipf struct {
@@ -276,7 +327,15 @@ type fixedStringChooserZipf struct {
 }
 
 func (c *fixedStringChooserZipf) NextArmnodString(g *guacamole.Guacamole) (uint64, bool) {
-	return distribute(g.Zipf(c.zp)-1, c.zp.N()), true
+	return distribute(g.Version(), g.Zipf(c.zp)-1, c.zp.N()), true
+}
+
+type fixedStringChooserWeighted struct {
+	table *guacamole.AliasTable
+}
+
+func (c *fixedStringChooserWeighted) NextArmnodString(g *guacamole.Guacamole) (uint64, bool) {
+	return distribute(g.Version(), uint64(g.Weighted(c.table)), uint64(c.table.Len())), true
 }
 
 type initFixedStringChooser struct {
@@ -287,7 +346,7 @@ type initFixedStringChooser struct {
 
 func (c *initFixedStringChooser) NextArmnodString(g *guacamole.Guacamole) (uint64, bool) {
 	if c.idx < c.limit {
-		x, done := distribute(c.idx, c.N), true
+		x, done := distribute(g.Version(), c.idx, c.N), true
 		c.idx++
 		return x, done
 	}
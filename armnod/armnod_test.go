@@ -1,6 +1,8 @@
 package armnod_test
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -64,6 +66,104 @@ func TestArmnodZipf(t *testing.T) {
 	require.True(len(strings) < 10)
 }
 
+func TestArmnodWeighted(t *testing.T) {
+	require := require.New(t)
+
+	weights := []float64{5, 0, 1, 0, 4}
+	c := armnod.Configuration{}
+	c.Charset = armnod.Default
+	c.StringChooser = armnod.ChooseFromFixedSetWeighted(guacamole.NewAliasTable(weights))
+	g := c.Generator()
+	require.NotNil(g)
+
+	strings := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		s, ok := g.String()
+		require.True(ok)
+		strings[s]++
+	}
+	require.Len(strings, 3)
+
+	// the weighted set is the same set of strings as the uniform one
+	c.StringChooser = armnod.ChooseFromFixedSet(uint64(len(weights)))
+	g = c.Generator()
+	uniform := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		s, ok := g.String()
+		require.True(ok)
+		uniform[s] = true
+	}
+	require.Len(uniform, len(weights))
+	for s, count := range strings {
+		require.True(uniform[s])
+		require.True(count > 500)
+	}
+}
+
+// armnodGoldens holds a digest of the strings each supported version
+// generates for each configuration in armnodVersionConfigurations.  A
+// version's entry must never change once it is released.
+var armnodGoldens = map[guacamole.Version][]string{
+	guacamole.Version1: {
+		"8cf0921d98b2f90c96b63d59597b857ce9312e9d183f3859ae004c592782ac8a",
+		"2134157bf51918faf4b61bb815dd9786d4aff25a9f1bfc45a3802241b985040c",
+		"bdfdd66e2ec20571cb939e640cd4c2287977e0aaf2a36e576ad20e8d8db06e07",
+		"d7b8c7669ea971d1d1aeb6fa0e55dbada2b1b0e10a782ac6597dbe55dfc19ecb",
+	},
+}
+
+func armnodVersionConfigurations(v guacamole.Version) []armnod.Configuration {
+	return []armnod.Configuration{
+		{Charset: armnod.Default, Version: v},
+		{
+			Charset:       armnod.HexLower,
+			StringChooser: armnod.ChooseFromFixedSet(1000),
+			LengthChooser: armnod.UniformLengthChooser{Min: 8, Max: 32},
+			Version:       v,
+		},
+		{
+			Charset:       armnod.Alphanumeric,
+			StringChooser: armnod.ChooseFromFixedSetZipf(guacamole.ZipfThetaVersion(100000, 0.99, v)),
+			Version:       v,
+		},
+		{
+			Charset:       armnod.Base64URL,
+			StringChooser: armnod.InitializeFixedSet(1000),
+			LengthChooser: armnod.ConstantLengthChooser{Length: 20},
+			Version:       v,
+		},
+	}
+}
+
+func TestArmnodVersionGoldens(t *testing.T) {
+	require := require.New(t)
+	for v := guacamole.Version1; v <= guacamole.LatestVersion; v++ {
+		golden, ok := armnodGoldens[v]
+		require.True(ok, "version %d has no golden output", v)
+		var digests []string
+		for _, c := range armnodVersionConfigurations(v) {
+			g := c.Generator()
+			require.Equal(v, g.Version())
+			g.Seed(1337)
+			h := sha256.New()
+			for i := 0; i < 1000; i++ {
+				s, ok := g.String()
+				require.True(ok)
+				h.Write([]byte(s))
+				h.Write([]byte{0})
+			}
+			digests = append(digests, hex.EncodeToString(h.Sum(nil)))
+		}
+		require.Equal(golden, digests, "version %d", v)
+	}
+
+	// the zero Version is Version1
+	require.Equal(guacamole.Version1, armnod.Configuration{Charset: armnod.Default}.Generator().Version())
+	require.Panics(func() {
+		armnod.Configuration{Charset: armnod.Default, Version: guacamole.LatestVersion + 1}.Generator()
+	})
+}
+
 var result string
 
 func BenchmarkArmnodDefault(b *testing.B) {
@@ -0,0 +1,214 @@
+package guacamole
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+)
+
+var errMarshaledAliasTable = errors.New("guacamole: invalid marshaled alias table")
+
+// aliasTableVersion is the first byte of the binary encoding of an AliasTable.
+const aliasTableVersion = 1
+
+// AliasTable picks one of K outcomes with arbitrary, fixed weights in constant
+// time.  The table is built once with Vose's alias method and may then be
+// shared by any number of generators; see the Weighted method.
+//
+// Each outcome is identified by its index into the weights the table was built
+// from.  Outcomes may optionally carry a string label.
+type AliasTable struct {
+	// threshold[i] is the probability of keeping column i, scaled to 2^64
+	threshold []uint64
+	// alias[i] is the outcome chosen when column i is not kept
+	alias  []int
+	labels []string
+}
+
+// NewAliasTable builds an AliasTable from the provided weights.  The weights
+// need not sum to one; outcome i is chosen with probability weights[i] divided
+// by the sum of the weights.  It panics if there are no weights, if any weight
+// is negative, NaN, or infinite, or if every weight is zero.
+func NewAliasTable(weights []float64) *AliasTable {
+	n := len(weights)
+	if n == 0 {
+		panic("guacamole: alias table requires at least one weight")
+	}
+	sum := 0.0
+	for _, w := range weights {
+		if !(w >= 0) || math.IsInf(w, 1) {
+			panic("guacamole: invalid alias table weight")
+		}
+		sum += w
+	}
+	if !(sum > 0) || math.IsInf(sum, 1) {
+		panic("guacamole: invalid alias table weights")
+	}
+
+	at := &AliasTable{
+		threshold: make([]uint64, n),
+		alias:     make([]int, n),
+	}
+	p := make([]float64, n)
+	var small, large []int
+	for i, w := range weights {
+		p[i] = w * float64(n) / sum
+		if p[i] < 1 {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+	for len(small) > 0 && len(large) > 0 {
+		l := small[len(small)-1]
+		small = small[:len(small)-1]
+		g := large[len(large)-1]
+		large = large[:len(large)-1]
+		at.setColumn(l, p[l], g)
+		p[g] = (p[g] + p[l]) - 1
+		if p[g] < 1 {
+			small = append(small, g)
+		} else {
+			large = append(large, g)
+		}
+	}
+	// Whatever remains is within rounding error of one.
+	for _, i := range large {
+		at.setColumn(i, 1, i)
+	}
+	for _, i := range small {
+		at.setColumn(i, 1, i)
+	}
+	return at
+}
+
+// NewLabeledAliasTable builds an AliasTable like NewAliasTable and attaches a
+// label to each outcome.  It panics if there is not exactly one label per
+// weight.
+func NewLabeledAliasTable(labels []string, weights []float64) *AliasTable {
+	if len(labels) != len(weights) {
+		panic("guacamole: alias table requires one label per weight")
+	}
+	at := NewAliasTable(weights)
+	at.labels = append([]string(nil), labels...)
+	return at
+}
+
+func (at *AliasTable) setColumn(i int, p float64, alias int) {
+	const scale = 1 << 64
+	if p >= 1 {
+		at.threshold[i] = math.MaxUint64
+		at.alias[i] = i
+		return
+	}
+	t := p * scale
+	if t >= scale {
+		t = math.Nextafter(scale, 0)
+	}
+	at.threshold[i] = uint64(t)
+	at.alias[i] = alias
+}
+
+// Len returns the number of outcomes in the table.
+func (at *AliasTable) Len() int {
+	return len(at.threshold)
+}
+
+// Label returns the label of outcome i, or the empty string if the table has
+// no labels.
+func (at *AliasTable) Label(i int) string {
+	if at.labels == nil {
+		return ""
+	}
+	return at.labels[i]
+}
+
+// Weighted returns the index of an outcome chosen from the provided
+// AliasTable.  It consumes exactly one Uint64:  the high bits of its product
+// with Len choose a column and the low bits decide between the column and its
+// alias.
+func (g *Guacamole) Weighted(at *AliasTable) int {
+	checkVersions(g.version, Version1)
+	column, coin := bits.Mul64(g.Uint64(), uint64(len(at.threshold)))
+	if coin < at.threshold[column] {
+		return int(column)
+	}
+	return at.alias[column]
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.  The built table is
+// encoded, rather than the weights, so that a table unmarshaled on another
+// machine makes exactly the same choices.
+func (at *AliasTable) MarshalBinary() ([]byte, error) {
+	buf := []byte{aliasTableVersion}
+	buf = binary.AppendUvarint(buf, uint64(len(at.threshold)))
+	for i := range at.threshold {
+		buf = binary.BigEndian.AppendUint64(buf, at.threshold[i])
+		buf = binary.AppendUvarint(buf, uint64(at.alias[i]))
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(at.labels)))
+	for _, label := range at.labels {
+		buf = binary.AppendUvarint(buf, uint64(len(label)))
+		buf = append(buf, label...)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (at *AliasTable) UnmarshalBinary(data []byte) error {
+	if len(data) < 1 || data[0] != aliasTableVersion {
+		return errMarshaledAliasTable
+	}
+	data = data[1:]
+	uvarint := func() (uint64, bool) {
+		x, n := binary.Uvarint(data)
+		if n <= 0 {
+			return 0, false
+		}
+		data = data[n:]
+		return x, true
+	}
+	n, ok := uvarint()
+	// every column takes at least 9 bytes
+	if !ok || n == 0 || n > uint64(len(data))/9 {
+		return errMarshaledAliasTable
+	}
+	threshold := make([]uint64, n)
+	alias := make([]int, n)
+	for i := range threshold {
+		if len(data) < 8 {
+			return errMarshaledAliasTable
+		}
+		threshold[i] = binary.BigEndian.Uint64(data)
+		data = data[8:]
+		a, ok := uvarint()
+		if !ok || a >= n {
+			return errMarshaledAliasTable
+		}
+		alias[i] = int(a)
+	}
+	numLabels, ok := uvarint()
+	if !ok || (numLabels != 0 && numLabels != n) {
+		return errMarshaledAliasTable
+	}
+	var labels []string
+	if numLabels > 0 {
+		labels = make([]string, n)
+	}
+	for i := range labels {
+		sz, ok := uvarint()
+		if !ok || sz > uint64(len(data)) {
+			return errMarshaledAliasTable
+		}
+		labels[i] = string(data[:sz])
+		data = data[sz:]
+	}
+	if len(data) != 0 {
+		return errMarshaledAliasTable
+	}
+	at.threshold = threshold
+	at.alias = alias
+	at.labels = labels
+	return nil
+}
@@ -0,0 +1,136 @@
+package guacamole_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"hack.systems/random/guacamole"
+)
+
+func TestAliasTable(t *testing.T) {
+	require := require.New(t)
+	weights := []float64{1, 0, 3.5, 10, 0.25, 2, 0, 7}
+	at := guacamole.NewAliasTable(weights)
+	require.Equal(len(weights), at.Len())
+	require.Equal("", at.Label(0))
+
+	sum := 0.0
+	for _, w := range weights {
+		sum += w
+	}
+	g := guacamole.New()
+	const n = 1000000
+	counts := make([]int, len(weights))
+	for i := 0; i < n; i++ {
+		counts[g.Weighted(at)]++
+	}
+	chi2 := 0.0
+	df := -1
+	for i, w := range weights {
+		if w == 0 {
+			require.Zero(counts[i], "outcome %d has zero weight", i)
+			continue
+		}
+		e := w / sum * n
+		d := float64(counts[i]) - e
+		chi2 += d * d / e
+		df++
+	}
+	// 99.9th percentile of chi-square with 5 degrees of freedom
+	require.Equal(5, df)
+	require.True(chi2 < 20.515, "chi2=%g", chi2)
+}
+
+func TestAliasTableSingleton(t *testing.T) {
+	require := require.New(t)
+	at := guacamole.NewAliasTable([]float64{42})
+	g := guacamole.New()
+	for i := 0; i < 100; i++ {
+		require.Equal(0, g.Weighted(at))
+	}
+}
+
+func TestAliasTableConsumption(t *testing.T) {
+	require := require.New(t)
+	at := guacamole.NewAliasTable([]float64{1, 2, 3})
+	g := guacamole.New()
+	for i := 0; i < 10; i++ {
+		g.Weighted(at)
+	}
+	_, offset := g.Position()
+	require.Equal(uint64(80), offset)
+}
+
+func TestAliasTableMarshal(t *testing.T) {
+	require := require.New(t)
+	weights := make([]float64, 1000)
+	labels := make([]string, 1000)
+	for i := range weights {
+		weights[i] = math.Pow(float64(i+1), -0.9)
+		labels[i] = string(rune('a' + i%26))
+	}
+	for _, at := range []*guacamole.AliasTable{
+		guacamole.NewAliasTable(weights),
+		guacamole.NewLabeledAliasTable(labels, weights),
+	} {
+		data, err := at.MarshalBinary()
+		require.NoError(err)
+		var loaded guacamole.AliasTable
+		require.NoError(loaded.UnmarshalBinary(data))
+		require.Equal(at, &loaded)
+
+		g1 := guacamole.New()
+		g2 := guacamole.New()
+		for i := 0; i < 10000; i++ {
+			x := g1.Weighted(at)
+			require.Equal(x, g2.Weighted(&loaded))
+			require.Equal(at.Label(x), loaded.Label(x))
+		}
+
+		// truncations and trailing garbage are rejected
+		for i := 0; i < len(data); i++ {
+			require.Error(loaded.UnmarshalBinary(data[:i]))
+		}
+		require.Error(loaded.UnmarshalBinary(append(data, 0)))
+	}
+}
+
+func TestAliasTableLabels(t *testing.T) {
+	require := require.New(t)
+	at := guacamole.NewLabeledAliasTable([]string{"read", "write", "scan"}, []float64{90, 9, 1})
+	require.Equal("read", at.Label(0))
+	require.Equal("write", at.Label(1))
+	require.Equal("scan", at.Label(2))
+	g := guacamole.New()
+	ops := make(map[string]int)
+	for i := 0; i < 100000; i++ {
+		ops[at.Label(g.Weighted(at))]++
+	}
+	require.InDelta(90000, ops["read"], 500)
+	require.InDelta(9000, ops["write"], 500)
+	require.InDelta(1000, ops["scan"], 200)
+}
+
+func TestAliasTableInvalid(t *testing.T) {
+	require := require.New(t)
+	require.Panics(func() { guacamole.NewAliasTable(nil) })
+	require.Panics(func() { guacamole.NewAliasTable([]float64{0, 0}) })
+	require.Panics(func() { guacamole.NewAliasTable([]float64{1, -1}) })
+	require.Panics(func() { guacamole.NewAliasTable([]float64{1, math.NaN()}) })
+	require.Panics(func() { guacamole.NewAliasTable([]float64{1, math.Inf(1)}) })
+	require.Panics(func() { guacamole.NewLabeledAliasTable([]string{"a"}, []float64{1, 2}) })
+}
+
+func BenchmarkAliasTable(b *testing.B) {
+	weights := make([]float64, 1000)
+	for i := range weights {
+		weights[i] = float64(i + 1)
+	}
+	at := guacamole.NewAliasTable(weights)
+	g := guacamole.New()
+	for n := 0; n < b.N; n++ {
+		g.Weighted(at)
+	}
+}
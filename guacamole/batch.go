@@ -0,0 +1,47 @@
+package guacamole
+
+import (
+	"encoding/binary"
+	"unsafe"
+)
+
+// The methods in this file fill a slice with the values the corresponding
+// single-value method would return, in order, leaving the generator in the
+// same state.  With cgo, they make one call across the cgo boundary per slice
+// instead of one per value, which is often more expensive than the value
+// itself.
+
+// Uint64Fill fills dst with the next len(dst) values of Uint64.
+func (g *Guacamole) Uint64Fill(dst []uint64) {
+	if len(dst) == 0 {
+		return
+	}
+	// generate the bytes in place and then decode each word on top of itself
+	bytes := unsafe.Slice((*byte)(unsafe.Pointer(&dst[0])), 8*len(dst))
+	g.Fill(bytes)
+	for i := range dst {
+		dst[i] = binary.BigEndian.Uint64(bytes[8*i:])
+	}
+}
+
+// Float64Fill fills dst with the next len(dst) values of Float64.
+func (g *Guacamole) Float64Fill(dst []float64) {
+	g.guac.doubles(dst)
+}
+
+// ZipfFill fills dst with the next len(dst) values of Zipf(zp).
+func (g *Guacamole) ZipfFill(zp *ZipfParams, dst []uint64) {
+	checkVersions(g.version, zp.version)
+	zp.gzp.fill(&g.guac, dst)
+}
+
+// ScrambleSlice sets dst[i] to Scramble(src[i]) for every element of src.  The
+// two slices may be the same slice, but must not otherwise overlap.  It panics
+// if dst is shorter than src.
+func (s *Scrambler) ScrambleSlice(dst, src []uint64) {
+	checkVersions(s.version, Version1)
+	if len(dst) < len(src) {
+		panic("guacamole: ScrambleSlice destination shorter than source")
+	}
+	s.scr.scrambleSlice(dst, src)
+}
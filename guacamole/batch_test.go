@@ -0,0 +1,136 @@
+package guacamole_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"hack.systems/random/guacamole"
+)
+
+// The batch methods must return exactly the values of the single-value
+// methods and leave the generator where they would have, including when the
+// stream does not start on a block boundary.
+func TestBatchMatchesSingle(t *testing.T) {
+	require := require.New(t)
+	for _, n := range []int{0, 1, 7, 8, 9, 100, 1000} {
+		for _, offset := range []uint64{0, 3, 60} {
+			single := guacamole.New()
+			batch := guacamole.New()
+			single.Seek(1337, offset)
+			batch.Seek(1337, offset)
+
+			u64 := make([]uint64, n)
+			batch.Uint64Fill(u64)
+			for i := range u64 {
+				require.Equal(single.Uint64(), u64[i], "n=%d offset=%d i=%d", n, offset, i)
+			}
+
+			f64 := make([]float64, n)
+			batch.Float64Fill(f64)
+			for i := range f64 {
+				require.Equal(single.Float64(), f64[i], "n=%d offset=%d i=%d", n, offset, i)
+			}
+
+			zp := guacamole.ZipfTheta(1000000, 0.99)
+			zipf := make([]uint64, n)
+			batch.ZipfFill(zp, zipf)
+			for i := range zipf {
+				require.Equal(single.Zipf(zp), zipf[i], "n=%d offset=%d i=%d", n, offset, i)
+			}
+
+			require.Equal(single.Uint64(), batch.Uint64())
+		}
+	}
+}
+
+func TestScrambleSlice(t *testing.T) {
+	require := require.New(t)
+	s := guacamole.NewScrambler()
+	s.Change(42)
+	src := make([]uint64, 1000)
+	guacamole.New().Uint64Fill(src)
+	src[0] = 0
+	dst := make([]uint64, len(src)+1)
+	s.ScrambleSlice(dst, src)
+	for i := range src {
+		require.Equal(s.Scramble(src[i]), dst[i])
+	}
+	require.Zero(dst[len(src)])
+	// in place
+	s.ScrambleSlice(src, src)
+	require.Equal(dst[:len(src)], src)
+	s.ScrambleSlice(nil, nil)
+	require.Panics(func() { s.ScrambleSlice(dst[:10], src) })
+}
+
+const batchSize = 1024
+
+func BenchmarkUint64(b *testing.B) {
+	g := guacamole.New()
+	sum := uint64(0)
+	for n := 0; n < b.N; n++ {
+		sum += g.Uint64()
+	}
+	result = sum
+}
+
+func BenchmarkUint64Fill(b *testing.B) {
+	g := guacamole.New()
+	dst := make([]uint64, batchSize)
+	for n := 0; n < b.N; n += batchSize {
+		g.Uint64Fill(dst)
+	}
+	result = dst[0]
+}
+
+func BenchmarkFloat64(b *testing.B) {
+	g := guacamole.New()
+	sum := 0.0
+	for n := 0; n < b.N; n++ {
+		sum += g.Float64()
+	}
+	result = uint64(sum)
+}
+
+func BenchmarkFloat64Fill(b *testing.B) {
+	g := guacamole.New()
+	dst := make([]float64, batchSize)
+	for n := 0; n < b.N; n += batchSize {
+		g.Float64Fill(dst)
+	}
+	result = uint64(dst[0])
+}
+
+func BenchmarkZipf(b *testing.B) {
+	zp := guacamole.ZipfTheta(1000000, 0.99)
+	g := guacamole.New()
+	sum := uint64(0)
+	for n := 0; n < b.N; n++ {
+		sum += g.Zipf(zp)
+	}
+	result = sum
+}
+
+func BenchmarkZipfFill(b *testing.B) {
+	zp := guacamole.ZipfTheta(1000000, 0.99)
+	g := guacamole.New()
+	dst := make([]uint64, batchSize)
+	for n := 0; n < b.N; n += batchSize {
+		g.ZipfFill(zp, dst)
+	}
+	result = dst[0]
+}
+
+func BenchmarkScrambleSlice(b *testing.B) {
+	s := guacamole.NewScrambler()
+	src := make([]uint64, batchSize)
+	for i := range src {
+		src[i] = uint64(i)
+	}
+	dst := make([]uint64, batchSize)
+	for n := 0; n < b.N; n += batchSize {
+		s.ScrambleSlice(dst, src)
+	}
+	result = dst[0]
+}
@@ -0,0 +1,98 @@
+package guacamole
+
+import (
+	"math/bits"
+)
+
+// The methods in this file draw bounded integers without modulo bias.  They
+// follow a fixed contract for how much guacamole they consume so that results
+// are reproducible across versions:
+//
+// Uint64n draws with Lemire's multiply-shift method.  Each attempt consumes
+// exactly one Uint64 (8 bytes) and computes the 128-bit product x*n.  The high
+// 64 bits are the result unless the low 64 bits are less than 2^64 mod n, in
+// which case the attempt is rejected and another is made.  The probability
+// that an attempt is rejected is less than n/2^64.  Int63n and IntRange are
+// Uint64n of the size of their range, offset into place.
+//
+// Shuffle is a Fisher-Yates shuffle that, for i from n-1 down to 1, swaps i
+// with Uint64n(i+1); it draws exactly n-1 values from Uint64n.  Perm shuffles
+// the identity permutation with Shuffle.
+
+// Uint64n returns a uniformly distributed integer in [0, n).  It panics if n
+// is zero.
+func (g *Guacamole) Uint64n(n uint64) uint64 {
+	checkVersions(g.version, Version1)
+	return g.uint64n(n)
+}
+
+// uint64n is Uint64n without the version check, for samplers that have made
+// it already.
+func (g *Guacamole) uint64n(n uint64) uint64 {
+	if n == 0 {
+		panic("guacamole: invalid argument to Uint64n")
+	}
+	hi, lo := bits.Mul64(g.Uint64(), n)
+	if lo < n {
+		threshold := -n % n
+		for lo < threshold {
+			hi, lo = bits.Mul64(g.Uint64(), n)
+		}
+	}
+	return hi
+}
+
+// Int63n returns a uniformly distributed integer in [0, n).  It panics if n
+// is not positive.
+func (g *Guacamole) Int63n(n int64) int64 {
+	checkVersions(g.version, Version1)
+	if n <= 0 {
+		panic("guacamole: invalid argument to Int63n")
+	}
+	return int64(g.uint64n(uint64(n)))
+}
+
+// IntRange returns a uniformly distributed integer in [lo, hi).  It panics if
+// hi is not greater than lo.
+func (g *Guacamole) IntRange(lo, hi int64) int64 {
+	checkVersions(g.version, Version1)
+	if hi <= lo {
+		panic("guacamole: invalid argument to IntRange")
+	}
+	return lo + int64(g.uint64n(uint64(hi)-uint64(lo)))
+}
+
+// Shuffle pseudo-randomizes the order of n elements.  swap swaps the elements
+// with indexes i and j.  It panics if n is negative.
+func (g *Guacamole) Shuffle(n int, swap func(i, j int)) {
+	checkVersions(g.version, Version1)
+	if n < 0 {
+		panic("guacamole: invalid argument to Shuffle")
+	}
+	g.shuffle(n, swap)
+}
+
+// shuffle is Shuffle without the version check or the check of n.
+func (g *Guacamole) shuffle(n int, swap func(i, j int)) {
+	for i := n - 1; i > 0; i-- {
+		j := int(g.uint64n(uint64(i) + 1))
+		swap(i, j)
+	}
+}
+
+// Perm returns a pseudo-random permutation of the integers [0, n).  It panics
+// if n is negative.
+func (g *Guacamole) Perm(n int) []int {
+	checkVersions(g.version, Version1)
+	if n < 0 {
+		panic("guacamole: invalid argument to Perm")
+	}
+	m := make([]int, n)
+	for i := range m {
+		m[i] = i
+	}
+	g.shuffle(n, func(i, j int) {
+		m[i], m[j] = m[j], m[i]
+	})
+	return m
+}
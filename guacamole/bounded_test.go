@@ -0,0 +1,105 @@
+package guacamole_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"hack.systems/random/guacamole"
+)
+
+// TestBoundedGolden guards the consumption contract documented in bounded.go.
+// If this test fails, previously generated data will not be reproducible.
+func TestBoundedGolden(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+	g.Seed(1337)
+	require.Equal(uint64(3), g.Uint64n(10))
+	require.Equal(uint64(921705081), g.Uint64n(1000000007))
+	require.Equal(uint64(5559789139996632174), g.Uint64n(1<<63+1))
+	require.Equal(int64(1), g.Int63n(100))
+	require.Equal(int64(-22), g.IntRange(-50, 50))
+	require.Equal(int64(-6308137847137107012), g.IntRange(-1<<63, 1<<63-1))
+	require.Equal([]int{9, 7, 8, 4, 6, 5, 2, 1, 3, 0}, g.Perm(10))
+	seed, offset := g.Position()
+	require.Equal(uint64(1337), seed)
+	require.Equal(uint64(16*8), offset)
+}
+
+func TestUint64nConsumption(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+
+	// n=1 never rejects, but still consumes a value
+	g.Seed(0)
+	require.Equal(uint64(0), g.Uint64n(1))
+	_, offset := g.Position()
+	require.Equal(uint64(8), offset)
+
+	// seed 9 rejects its first attempt when n is 3*2^62
+	g.Seed(9)
+	x := g.Uint64n(3 << 62)
+	_, offset = g.Position()
+	require.Equal(uint64(16), offset)
+	g.Seek(9, 8)
+	require.Equal(g.Uint64n(3<<62), x)
+}
+
+func TestUint64nUniform(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+	g.Seed(42)
+	const n = 7
+	const draws = 700000
+	counts := make([]float64, n)
+	for i := 0; i < draws; i++ {
+		counts[g.Uint64n(n)]++
+	}
+	chi2 := 0.0
+	expected := float64(draws) / n
+	for _, c := range counts {
+		chi2 += (c - expected) * (c - expected) / expected
+	}
+	// 99.9th percentile of chi-square with 6 degrees of freedom
+	require.True(chi2 < 22.458, "chi2=%g", chi2)
+}
+
+func TestIntRange(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+	seen := make(map[int64]bool)
+	for i := 0; i < 1000; i++ {
+		x := g.IntRange(-3, 3)
+		require.True(x >= -3 && x < 3)
+		seen[x] = true
+	}
+	require.Len(seen, 6)
+	require.Panics(func() { g.IntRange(3, 3) })
+	require.Panics(func() { g.Int63n(0) })
+	require.Panics(func() { g.Uint64n(0) })
+}
+
+func TestShufflePerm(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+	for _, n := range []int{0, 1, 2, 10, 1000} {
+		p := g.Perm(n)
+		require.Len(p, n)
+		sort.Ints(p)
+		for i := range p {
+			require.Equal(i, p[i])
+		}
+	}
+
+	g.Seed(5)
+	p := g.Perm(52)
+	g.Seed(5)
+	deck := make([]int, 52)
+	for i := range deck {
+		deck[i] = i
+	}
+	g.Shuffle(len(deck), func(i, j int) { deck[i], deck[j] = deck[j], deck[i] })
+	require.Equal(p, deck)
+	require.Panics(func() { g.Perm(-1) })
+}
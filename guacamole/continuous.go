@@ -0,0 +1,350 @@
+package guacamole
+
+import (
+	"math"
+)
+
+// The samplers in this file draw from continuous distributions.  Like Zipf,
+// each distribution is described by a params struct that is computed once and
+// may be shared by any number of generators.  All randomness comes from the
+// generator passed in, so a sample is reproducible by seeding or seeking the
+// generator to the same place.
+//
+// Every sampler consumes guacamole in units of 8 bytes:  one Uint64 or one
+// Float64 at a time.  The number of units consumed by a single sample depends
+// upon the values drawn (all of these methods reject some draws), but it is a
+// deterministic function of the stream.
+//
+// NormFloat64 and ExpFloat64 use the ziggurat method of Marsaglia and Tsang
+// with 256 layers.  Each attempt consumes one Uint64; the low 8 bits select the
+// layer and the remaining 56 bits select the point within it.  Roughly 99% of
+// samples are accepted on the first attempt.  Gamma uses the method of
+// Marsaglia and Tsang on top of NormFloat64, and the remaining distributions
+// are transformations of these.
+
+const (
+	zigLayers = 256
+	// zigScale is the range of the 56 bits used to pick a point in a layer.
+	zigScale = 1 << 56
+
+	zigNormalR = 3.6541528853610088
+	zigNormalV = 4.92867323399e-3
+	zigExpR    = 7.69711747013104972
+	zigExpV    = 3.949659822581572e-3
+)
+
+var (
+	zigNormalK [zigLayers]uint64
+	zigNormalW [zigLayers]float64
+	zigNormalF [zigLayers]float64
+	zigExpK    [zigLayers]uint64
+	zigExpW    [zigLayers]float64
+	zigExpF    [zigLayers]float64
+)
+
+func init() {
+	// normal tables; the normal sampler has a sign bit so points within a
+	// layer are scaled by half as much.
+	const m1 = zigScale / 2
+	dn := zigNormalR
+	tn := dn
+	q := zigNormalV / math.Exp(-0.5*dn*dn)
+	zigNormalK[0] = uint64((dn / q) * m1)
+	zigNormalK[1] = 0
+	zigNormalW[0] = q / m1
+	zigNormalW[zigLayers-1] = dn / m1
+	zigNormalF[0] = 1
+	zigNormalF[zigLayers-1] = math.Exp(-0.5 * dn * dn)
+	for i := zigLayers - 2; i >= 1; i-- {
+		dn = math.Sqrt(-2 * math.Log(zigNormalV/dn+math.Exp(-0.5*dn*dn)))
+		zigNormalK[i+1] = uint64((dn / tn) * m1)
+		tn = dn
+		zigNormalF[i] = math.Exp(-0.5 * dn * dn)
+		zigNormalW[i] = dn / m1
+	}
+
+	// exponential tables
+	const m2 = zigScale
+	de := zigExpR
+	te := de
+	q = zigExpV / math.Exp(-de)
+	zigExpK[0] = uint64((de / q) * m2)
+	zigExpK[1] = 0
+	zigExpW[0] = q / m2
+	zigExpW[zigLayers-1] = de / m2
+	zigExpF[0] = 1
+	zigExpF[zigLayers-1] = math.Exp(-de)
+	for i := zigLayers - 2; i >= 1; i-- {
+		de = -math.Log(zigExpV/de + math.Exp(-de))
+		zigExpK[i+1] = uint64((de / te) * m2)
+		te = de
+		zigExpF[i] = math.Exp(-de)
+		zigExpW[i] = de / m2
+	}
+}
+
+// openFloat64 returns a float64 in (0, 1] so that its logarithm is finite.
+func (g *Guacamole) openFloat64() float64 {
+	return 1 - g.Float64()
+}
+
+// NormFloat64 returns a normally distributed float64 with mean 0 and standard
+// deviation 1.
+func (g *Guacamole) NormFloat64() float64 {
+	checkVersions(g.version, Version1)
+	return g.normFloat64()
+}
+
+// normFloat64 is NormFloat64 without the version check, for samplers that
+// have made it already.
+func (g *Guacamole) normFloat64() float64 {
+	for {
+		u := g.Uint64()
+		i := u & (zigLayers - 1)
+		j := int64(u) >> 8
+		x := float64(j) * zigNormalW[i]
+		a := uint64(j)
+		if j < 0 {
+			a = uint64(-j)
+		}
+		if a < zigNormalK[i] {
+			return x
+		}
+		if i == 0 {
+			// sample from the tail beyond zigNormalR
+			for {
+				x = -math.Log(g.openFloat64()) / zigNormalR
+				y := -math.Log(g.openFloat64())
+				if y+y >= x*x {
+					break
+				}
+			}
+			if j < 0 {
+				return -zigNormalR - x
+			}
+			return zigNormalR + x
+		}
+		if zigNormalF[i]+g.Float64()*(zigNormalF[i-1]-zigNormalF[i]) < math.Exp(-0.5*x*x) {
+			return x
+		}
+	}
+}
+
+// ExpFloat64 returns an exponentially distributed float64 with rate 1.
+func (g *Guacamole) ExpFloat64() float64 {
+	checkVersions(g.version, Version1)
+	return g.expFloat64()
+}
+
+// expFloat64 is ExpFloat64 without the version check, for samplers that have
+// made it already.
+func (g *Guacamole) expFloat64() float64 {
+	for {
+		u := g.Uint64()
+		i := u & (zigLayers - 1)
+		j := u >> 8
+		x := float64(j) * zigExpW[i]
+		if j < zigExpK[i] {
+			return x
+		}
+		if i == 0 {
+			// the tail is memoryless
+			return zigExpR - math.Log(g.openFloat64())
+		}
+		if zigExpF[i]+g.Float64()*(zigExpF[i-1]-zigExpF[i]) < math.Exp(-x) {
+			return x
+		}
+	}
+}
+
+// NormalParams specify a normal distribution.
+type NormalParams struct {
+	mean   float64
+	stddev float64
+}
+
+// NormalMeanStddev returns NormalParams with the given mean and standard
+// deviation.  It panics if stddev is negative.
+func NormalMeanStddev(mean, stddev float64) *NormalParams {
+	if !(stddev >= 0) {
+		panic("guacamole: invalid standard deviation")
+	}
+	return &NormalParams{mean: mean, stddev: stddev}
+}
+
+// Normal returns a sample from the provided NormalParams.
+func (g *Guacamole) Normal(np *NormalParams) float64 {
+	checkVersions(g.version, Version1)
+	return np.mean + np.stddev*g.normFloat64()
+}
+
+// ExponentialParams specify an exponential distribution.
+type ExponentialParams struct {
+	scale float64
+}
+
+// ExponentialRate returns ExponentialParams with the given rate (often
+// written lambda).  The mean of the distribution is 1/rate.  It panics if rate
+// is not positive.
+func ExponentialRate(rate float64) *ExponentialParams {
+	if !(rate > 0) {
+		panic("guacamole: invalid exponential rate")
+	}
+	return &ExponentialParams{scale: 1 / rate}
+}
+
+// Exponential returns a sample from the provided ExponentialParams.
+func (g *Guacamole) Exponential(ep *ExponentialParams) float64 {
+	checkVersions(g.version, Version1)
+	return g.expFloat64() * ep.scale
+}
+
+// LognormalParams specify a lognormal distribution:  the distribution of
+// exp(X) where X is normally distributed.
+type LognormalParams struct {
+	mu    float64
+	sigma float64
+}
+
+// LognormalMuSigma returns LognormalParams whose logarithm has mean mu and
+// standard deviation sigma.  It panics if sigma is negative.
+func LognormalMuSigma(mu, sigma float64) *LognormalParams {
+	if !(sigma >= 0) {
+		panic("guacamole: invalid lognormal sigma")
+	}
+	return &LognormalParams{mu: mu, sigma: sigma}
+}
+
+// Lognormal returns a sample from the provided LognormalParams.
+func (g *Guacamole) Lognormal(lp *LognormalParams) float64 {
+	checkVersions(g.version, Version1)
+	return math.Exp(lp.mu + lp.sigma*g.normFloat64())
+}
+
+// GammaParams specify a gamma distribution.  The constants used by the
+// Marsaglia-Tsang method are computed once, when the params are created.
+type GammaParams struct {
+	scale float64
+	// 1/shape when shape < 1; otherwise zero
+	boost float64
+	// d = a - 1/3 and c = 1/sqrt(9d), where a = max(shape, shape + 1)
+	d float64
+	c float64
+}
+
+// GammaShapeScale returns GammaParams with the given shape (k) and scale
+// (theta).  The mean of the distribution is shape*scale.  It panics if either
+// parameter is not positive.
+func GammaShapeScale(shape, scale float64) *GammaParams {
+	if !(shape > 0) || !(scale > 0) {
+		panic("guacamole: invalid gamma parameters")
+	}
+	gp := &GammaParams{scale: scale}
+	if shape < 1 {
+		// Gamma(a) = Gamma(a+1) * U^(1/a)
+		gp.boost = 1 / shape
+		shape++
+	}
+	gp.d = shape - 1.0/3.0
+	gp.c = 1 / math.Sqrt(9*gp.d)
+	return gp
+}
+
+// Gamma returns a sample from the provided GammaParams.
+func (g *Guacamole) Gamma(gp *GammaParams) float64 {
+	checkVersions(g.version, Version1)
+	return g.gamma(gp) * gp.scale
+}
+
+// gamma returns a sample from the provided GammaParams with unit scale.
+func (g *Guacamole) gamma(gp *GammaParams) float64 {
+	var v float64
+	for {
+		x := g.normFloat64()
+		v = 1 + gp.c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := g.openFloat64()
+		x2 := x * x
+		if u < 1-0.0331*x2*x2 || math.Log(u) < 0.5*x2+gp.d*(1-v+math.Log(v)) {
+			break
+		}
+	}
+	x := gp.d * v
+	if gp.boost != 0 {
+		x *= math.Pow(g.openFloat64(), gp.boost)
+	}
+	return x
+}
+
+// BetaParams specify a beta distribution.
+type BetaParams struct {
+	a GammaParams
+	b GammaParams
+}
+
+// BetaShape returns BetaParams with the given shape parameters (alpha and
+// beta).  It panics if either parameter is not positive.
+func BetaShape(alpha, beta float64) *BetaParams {
+	if !(alpha > 0) || !(beta > 0) {
+		panic("guacamole: invalid beta parameters")
+	}
+	return &BetaParams{
+		a: *GammaShapeScale(alpha, 1),
+		b: *GammaShapeScale(beta, 1),
+	}
+}
+
+// Beta returns a sample from the provided BetaParams.  It draws X from
+// Gamma(alpha) and then Y from Gamma(beta) and returns X/(X+Y).
+func (g *Guacamole) Beta(bp *BetaParams) float64 {
+	checkVersions(g.version, Version1)
+	x := g.gamma(&bp.a)
+	y := g.gamma(&bp.b)
+	return x / (x + y)
+}
+
+// ParetoParams specify a Pareto (type I) distribution.
+type ParetoParams struct {
+	scale    float64
+	invShape float64
+}
+
+// ParetoScaleShape returns ParetoParams with the given scale (the minimum
+// value, x_m) and shape (alpha).  It panics if either parameter is not
+// positive.
+func ParetoScaleShape(scale, shape float64) *ParetoParams {
+	if !(scale > 0) || !(shape > 0) {
+		panic("guacamole: invalid Pareto parameters")
+	}
+	return &ParetoParams{scale: scale, invShape: 1 / shape}
+}
+
+// Pareto returns a sample from the provided ParetoParams.
+func (g *Guacamole) Pareto(pp *ParetoParams) float64 {
+	checkVersions(g.version, Version1)
+	return pp.scale * math.Exp(g.expFloat64()*pp.invShape)
+}
+
+// WeibullParams specify a Weibull distribution.
+type WeibullParams struct {
+	invShape float64
+	scale    float64
+}
+
+// WeibullShapeScale returns WeibullParams with the given shape (k) and scale
+// (lambda).  It panics if either parameter is not positive.
+func WeibullShapeScale(shape, scale float64) *WeibullParams {
+	if !(shape > 0) || !(scale > 0) {
+		panic("guacamole: invalid Weibull parameters")
+	}
+	return &WeibullParams{invShape: 1 / shape, scale: scale}
+}
+
+// Weibull returns a sample from the provided WeibullParams.
+func (g *Guacamole) Weibull(wp *WeibullParams) float64 {
+	checkVersions(g.version, Version1)
+	return wp.scale * math.Pow(g.expFloat64(), wp.invShape)
+}
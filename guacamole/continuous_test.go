@@ -0,0 +1,210 @@
+package guacamole_test
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"hack.systems/random/guacamole"
+)
+
+const continuousSamples = 200000
+
+// checkContinuous draws samples, compares the sample mean and variance to the
+// theoretical values, and computes the Kolmogorov-Smirnov statistic against
+// cdf.  The mean must be within five standard errors.  The tolerance on the
+// variance is looser because its standard error depends on the fourth moment.
+func checkContinuous(t *testing.T, name string, draw func(g *guacamole.Guacamole) float64, mean, variance float64, cdf func(float64) float64) {
+	require := require.New(t)
+	g := guacamole.New()
+	g.Seed(0xc0ffee)
+	samples := make([]float64, continuousSamples)
+	sum := 0.0
+	for i := range samples {
+		samples[i] = draw(g)
+		require.False(math.IsNaN(samples[i]) || math.IsInf(samples[i], 0), "%s: sample %d is %g", name, i, samples[i])
+		sum += samples[i]
+	}
+	n := float64(len(samples))
+	m := sum / n
+	ss := 0.0
+	for _, x := range samples {
+		ss += (x - m) * (x - m)
+	}
+	v := ss / (n - 1)
+	require.InDelta(mean, m, 5*math.Sqrt(variance/n), "%s: mean", name)
+	require.InEpsilon(variance, v, 0.05, "%s: variance", name)
+
+	sort.Float64s(samples)
+	d := 0.0
+	for i, x := range samples {
+		f := cdf(x)
+		d = math.Max(d, math.Max(f-float64(i)/n, float64(i+1)/n-f))
+	}
+	// the critical value of the KS statistic for alpha = 0.001
+	require.True(d < 1.95/math.Sqrt(n), "%s: KS statistic %g", name, d)
+}
+
+func normalCDF(mean, stddev float64) func(float64) float64 {
+	return func(x float64) float64 {
+		return 0.5 * math.Erfc(-(x-mean)/(stddev*math.Sqrt2))
+	}
+}
+
+func TestNormal(t *testing.T) {
+	checkContinuous(t, "standard normal", (*guacamole.Guacamole).NormFloat64, 0, 1, normalCDF(0, 1))
+	np := guacamole.NormalMeanStddev(100, 15)
+	checkContinuous(t, "normal", func(g *guacamole.Guacamole) float64 {
+		return g.Normal(np)
+	}, 100, 225, normalCDF(100, 15))
+}
+
+// TestNormalTail checks that the ziggurat base layer produces the tail in the
+// right proportion.
+func TestNormalTail(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+	const n = 4000000
+	tail := 0
+	for i := 0; i < n; i++ {
+		if math.Abs(g.NormFloat64()) > 3.6541528853610088 {
+			tail++
+		}
+	}
+	p := math.Erfc(3.6541528853610088 / math.Sqrt2)
+	require.InDelta(p*n, float64(tail), 5*math.Sqrt(p*n))
+}
+
+func TestExponential(t *testing.T) {
+	expCDF := func(rate float64) func(float64) float64 {
+		return func(x float64) float64 {
+			return -math.Expm1(-rate * x)
+		}
+	}
+	checkContinuous(t, "standard exponential", (*guacamole.Guacamole).ExpFloat64, 1, 1, expCDF(1))
+	ep := guacamole.ExponentialRate(0.25)
+	checkContinuous(t, "exponential", func(g *guacamole.Guacamole) float64 {
+		return g.Exponential(ep)
+	}, 4, 16, expCDF(0.25))
+}
+
+func TestLognormal(t *testing.T) {
+	const mu = 1
+	const sigma = 0.5
+	lp := guacamole.LognormalMuSigma(mu, sigma)
+	mean := math.Exp(mu + sigma*sigma/2)
+	variance := math.Expm1(sigma*sigma) * math.Exp(2*mu+sigma*sigma)
+	checkContinuous(t, "lognormal", func(g *guacamole.Guacamole) float64 {
+		return g.Lognormal(lp)
+	}, mean, variance, func(x float64) float64 {
+		return normalCDF(mu, sigma)(math.Log(x))
+	})
+}
+
+func TestGamma(t *testing.T) {
+	// shape 3 is the Erlang distribution, with a closed-form CDF
+	gp := guacamole.GammaShapeScale(3, 2)
+	checkContinuous(t, "gamma k=3", func(g *guacamole.Guacamole) float64 {
+		return g.Gamma(gp)
+	}, 6, 12, func(x float64) float64 {
+		y := x / 2
+		return 1 - math.Exp(-y)*(1+y+y*y/2)
+	})
+	// shape 1/2 is a scaled chi-square with one degree of freedom
+	gp = guacamole.GammaShapeScale(0.5, 3)
+	checkContinuous(t, "gamma k=0.5", func(g *guacamole.Guacamole) float64 {
+		return g.Gamma(gp)
+	}, 1.5, 4.5, func(x float64) float64 {
+		return math.Erf(math.Sqrt(x / 3))
+	})
+}
+
+func TestBeta(t *testing.T) {
+	bp := guacamole.BetaShape(2, 3)
+	checkContinuous(t, "beta(2,3)", func(g *guacamole.Guacamole) float64 {
+		return g.Beta(bp)
+	}, 0.4, 0.04, func(x float64) float64 {
+		// the regularized incomplete beta function for integer parameters
+		return 6*x*x - 8*x*x*x + 3*x*x*x*x
+	})
+	// the arcsine distribution
+	bp = guacamole.BetaShape(0.5, 0.5)
+	checkContinuous(t, "beta(0.5,0.5)", func(g *guacamole.Guacamole) float64 {
+		return g.Beta(bp)
+	}, 0.5, 0.125, func(x float64) float64 {
+		return 2 / math.Pi * math.Asin(math.Sqrt(x))
+	})
+}
+
+func TestPareto(t *testing.T) {
+	// shape 5 so that the variance is finite and the sample variance settles
+	pp := guacamole.ParetoScaleShape(2, 5)
+	checkContinuous(t, "pareto", func(g *guacamole.Guacamole) float64 {
+		return g.Pareto(pp)
+	}, 2.5, 5.0/12.0, func(x float64) float64 {
+		return 1 - math.Pow(2/x, 5)
+	})
+}
+
+func TestWeibull(t *testing.T) {
+	const k = 1.5
+	const lambda = 3
+	wp := guacamole.WeibullShapeScale(k, lambda)
+	g1 := math.Gamma(1 + 1/k)
+	g2 := math.Gamma(1 + 2/k)
+	checkContinuous(t, "weibull", func(g *guacamole.Guacamole) float64 {
+		return g.Weibull(wp)
+	}, lambda*g1, lambda*lambda*(g2-g1*g1), func(x float64) float64 {
+		return -math.Expm1(-math.Pow(x/lambda, k))
+	})
+}
+
+func TestContinuousReproducible(t *testing.T) {
+	require := require.New(t)
+	gp := guacamole.GammaShapeScale(0.7, 1)
+	g := guacamole.New()
+	g.Seed(99)
+	expected := make([]float64, 1000)
+	for i := range expected {
+		expected[i] = g.Gamma(gp)
+	}
+	g.Seed(99)
+	for i := range expected {
+		require.Equal(expected[i], g.Gamma(gp))
+	}
+}
+
+func TestContinuousInvalid(t *testing.T) {
+	require := require.New(t)
+	require.Panics(func() { guacamole.NormalMeanStddev(0, -1) })
+	require.Panics(func() { guacamole.ExponentialRate(0) })
+	require.Panics(func() { guacamole.LognormalMuSigma(0, math.NaN()) })
+	require.Panics(func() { guacamole.GammaShapeScale(0, 1) })
+	require.Panics(func() { guacamole.BetaShape(1, -1) })
+	require.Panics(func() { guacamole.ParetoScaleShape(1, 0) })
+	require.Panics(func() { guacamole.WeibullShapeScale(1, 0) })
+}
+
+func BenchmarkNormFloat64(b *testing.B) {
+	g := guacamole.New()
+	for n := 0; n < b.N; n++ {
+		g.NormFloat64()
+	}
+}
+
+func BenchmarkExpFloat64(b *testing.B) {
+	g := guacamole.New()
+	for n := 0; n < b.N; n++ {
+		g.ExpFloat64()
+	}
+}
+
+func BenchmarkGamma(b *testing.B) {
+	gp := guacamole.GammaShapeScale(2.5, 1)
+	g := guacamole.New()
+	for n := 0; n < b.N; n++ {
+		g.Gamma(gp)
+	}
+}
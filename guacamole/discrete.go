@@ -0,0 +1,473 @@
+package guacamole
+
+import (
+	"math"
+)
+
+// The samplers in this file draw from discrete distributions.  They follow
+// the conventions of continuous.go:  params are computed once, every draw
+// takes its randomness from the generator passed in, and guacamole is consumed
+// 8 bytes at a time so that the amount consumed is a deterministic function of
+// the stream.
+//
+// Poisson uses the multiplication method for small lambda, consuming k+1
+// Float64s to return k, and Hörmann's transformed rejection with squeeze
+// (PTRS) when lambda is at least 10.  Binomial uses inversion, consuming one
+// Float64 per pass, when the mean of the smaller tail is less than 30, and the
+// BTPE algorithm of Kachitvichyanukul and Schmeiser otherwise.  Geometric is an
+// inversion and consumes exactly one Float64.  NegativeBinomial is a gamma
+// mixture of Poissons.  Hypergeometric simulates the draws one at a time with
+// Uint64n when fewer than 10 items are drawn, and uses Stadlober's ratio of
+// uniforms (HRUA) otherwise.
+
+// logFactorial returns log(k!).
+func logFactorial(k float64) float64 {
+	lg, _ := math.Lgamma(k + 1)
+	return lg
+}
+
+// PoissonParams specify a Poisson distribution.
+type PoissonParams struct {
+	lambda float64
+	// exp(-lambda), for the multiplication method
+	enlam float64
+	// constants for PTRS
+	loglam   float64
+	a        float64
+	b        float64
+	invalpha float64
+	vr       float64
+}
+
+// poissonThreshold is the lambda at which Poisson switches to PTRS.
+const poissonThreshold = 10
+
+// PoissonLambda returns PoissonParams with the given mean.  It panics if
+// lambda is negative.
+func PoissonLambda(lambda float64) *PoissonParams {
+	pp := &PoissonParams{}
+	pp.init(lambda)
+	return pp
+}
+
+func (pp *PoissonParams) init(lambda float64) {
+	if !(lambda >= 0) || math.IsInf(lambda, 1) {
+		panic("guacamole: invalid Poisson lambda")
+	}
+	pp.lambda = lambda
+	if lambda < poissonThreshold {
+		pp.enlam = math.Exp(-lambda)
+		return
+	}
+	slam := math.Sqrt(lambda)
+	pp.loglam = math.Log(lambda)
+	pp.b = 0.931 + 2.53*slam
+	pp.a = -0.059 + 0.02483*pp.b
+	pp.invalpha = 1.1239 + 1.1328/(pp.b-3.4)
+	pp.vr = 0.9277 - 3.6224/(pp.b-2)
+}
+
+// Poisson returns a sample from the provided PoissonParams.
+func (g *Guacamole) Poisson(pp *PoissonParams) uint64 {
+	checkVersions(g.version, Version1)
+	return g.poisson(pp)
+}
+
+// poisson is Poisson without the version check, for samplers that have made
+// it already.
+func (g *Guacamole) poisson(pp *PoissonParams) uint64 {
+	if pp.lambda == 0 {
+		return 0
+	}
+	if pp.lambda < poissonThreshold {
+		k := uint64(0)
+		prod := g.Float64()
+		for prod > pp.enlam {
+			k++
+			prod *= g.Float64()
+		}
+		return k
+	}
+	for {
+		u := g.Float64() - 0.5
+		v := g.Float64()
+		us := 0.5 - math.Abs(u)
+		k := math.Floor((2*pp.a/us+pp.b)*u + pp.lambda + 0.43)
+		if us >= 0.07 && v <= pp.vr {
+			return uint64(k)
+		}
+		if k < 0 || (us < 0.013 && v > us) {
+			continue
+		}
+		if math.Log(v)+math.Log(pp.invalpha)-math.Log(pp.a/(us*us)+pp.b) <= -pp.lambda+k*pp.loglam-logFactorial(k) {
+			return uint64(k)
+		}
+	}
+}
+
+// BinomialParams specify a binomial distribution.
+type BinomialParams struct {
+	n uint64
+	p float64
+	// the smaller of p and 1-p, and its complement
+	r float64
+	q float64
+	// constants for inversion
+	qn    float64
+	bound float64
+	// constants for BTPE
+	btpe bool
+	m    float64
+	nrq  float64
+	xm   float64
+	xl   float64
+	xr   float64
+	c    float64
+	laml float64
+	lamr float64
+	p1   float64
+	p2   float64
+	p3   float64
+	p4   float64
+}
+
+// BinomialNP returns BinomialParams for the number of successes in n trials
+// that each succeed with probability p.  It panics if p is not in [0, 1].
+func BinomialNP(n uint64, p float64) *BinomialParams {
+	if !(p >= 0 && p <= 1) {
+		panic("guacamole: invalid binomial probability")
+	}
+	bp := &BinomialParams{n: n, p: p}
+	bp.r = math.Min(p, 1-p)
+	bp.q = 1 - bp.r
+	fn := float64(n)
+	if fn*bp.r < 30 {
+		bp.qn = math.Exp(fn * math.Log(bp.q))
+		np := fn * bp.r
+		bp.bound = math.Min(fn, np+10*math.Sqrt(np*bp.q+1))
+		return bp
+	}
+	r, q := bp.r, bp.q
+	fm := fn*r + r
+	bp.btpe = true
+	bp.m = math.Floor(fm)
+	bp.nrq = fn * r * q
+	bp.p1 = math.Floor(2.195*math.Sqrt(bp.nrq)-4.6*q) + 0.5
+	bp.xm = bp.m + 0.5
+	bp.xl = bp.xm - bp.p1
+	bp.xr = bp.xm + bp.p1
+	bp.c = 0.134 + 20.5/(15.3+bp.m)
+	a := (fm - bp.xl) / (fm - bp.xl*r)
+	bp.laml = a * (1 + a/2)
+	a = (bp.xr - fm) / (bp.xr * q)
+	bp.lamr = a * (1 + a/2)
+	bp.p2 = bp.p1 * (1 + 2*bp.c)
+	bp.p3 = bp.p2 + bp.c/bp.laml
+	bp.p4 = bp.p3 + bp.c/bp.lamr
+	return bp
+}
+
+// Binomial returns a sample from the provided BinomialParams.
+func (g *Guacamole) Binomial(bp *BinomialParams) uint64 {
+	checkVersions(g.version, Version1)
+	if bp.n == 0 || bp.r == 0 {
+		if bp.p == 1 {
+			return bp.n
+		}
+		return 0
+	}
+	var y uint64
+	if bp.btpe {
+		y = g.binomialBTPE(bp)
+	} else {
+		y = g.binomialInversion(bp)
+	}
+	if bp.p > 0.5 {
+		y = bp.n - y
+	}
+	return y
+}
+
+func (g *Guacamole) binomialInversion(bp *BinomialParams) uint64 {
+	x := 0.0
+	px := bp.qn
+	u := g.Float64()
+	for u > px {
+		x++
+		if x > bp.bound {
+			x = 0
+			px = bp.qn
+			u = g.Float64()
+		} else {
+			u -= px
+			px = ((float64(bp.n) - x + 1) * bp.r * px) / (x * bp.q)
+		}
+	}
+	return uint64(x)
+}
+
+// binomialBTPE follows the steps of the published algorithm; the labels are
+// the step numbers from the paper.
+func (g *Guacamole) binomialBTPE(bp *BinomialParams) uint64 {
+	fn := float64(bp.n)
+	r, q, m := bp.r, bp.q, bp.m
+	var u, v, x, y float64
+Step10:
+	u = g.Float64() * bp.p4
+	v = g.Float64()
+	if u <= bp.p1 {
+		y = math.Floor(bp.xm - bp.p1*v + u)
+		goto Step60
+	}
+	// Step20:  the parallelograms
+	if u <= bp.p2 {
+		x = bp.xl + (u-bp.p1)/bp.c
+		v = v*bp.c + 1 - math.Abs(m-x+0.5)/bp.p1
+		if v > 1 {
+			goto Step10
+		}
+		y = math.Floor(x)
+		goto Step50
+	}
+	// Step30:  the left exponential tail
+	if u <= bp.p3 {
+		y = math.Floor(bp.xl + math.Log(v)/bp.laml)
+		if y < 0 || v == 0 {
+			goto Step10
+		}
+		v = v * (u - bp.p2) * bp.laml
+		goto Step50
+	}
+	// Step40:  the right exponential tail
+	y = math.Floor(bp.xr - math.Log(v)/bp.lamr)
+	if y > fn || v == 0 {
+		goto Step10
+	}
+	v = v * (u - bp.p3) * bp.lamr
+Step50:
+	if k := math.Abs(y - m); k <= 20 || k >= bp.nrq/2-1 {
+		// evaluate the ratio f(y)/f(m) by recursion
+		s := r / q
+		a := s * (fn + 1)
+		f := 1.0
+		if m < y {
+			for i := m + 1; i <= y; i++ {
+				f *= a/i - s
+			}
+		} else if m > y {
+			for i := y + 1; i <= m; i++ {
+				f /= a/i - s
+			}
+		}
+		if v > f {
+			goto Step10
+		}
+	} else {
+		// squeeze using upper and lower bounds on log(f(y))
+		rho := (k / bp.nrq) * ((k*(k/3+0.625)+0.16666666666666666)/bp.nrq + 0.5)
+		t := -k * k / (2 * bp.nrq)
+		A := math.Log(v)
+		if A < t-rho {
+			goto Step60
+		}
+		if A > t+rho {
+			goto Step10
+		}
+		x1 := y + 1
+		f1 := m + 1
+		z := fn + 1 - m
+		w := fn - y + 1
+		if A > bp.xm*math.Log(f1/x1)+(fn-m+0.5)*math.Log(z/w)+(y-m)*math.Log(w*r/(x1*q))+
+			stirlingCorrection(f1)+stirlingCorrection(z)+stirlingCorrection(x1)+stirlingCorrection(w) {
+			goto Step10
+		}
+	}
+Step60:
+	return uint64(y)
+}
+
+// stirlingCorrection is the series correction term BTPE applies for each of
+// the four factorials in its final acceptance test.
+func stirlingCorrection(x float64) float64 {
+	x2 := x * x
+	return (13680 - (462-(132-(99-140/x2)/x2)/x2)/x2) / x / 166320
+}
+
+// GeometricParams specify a geometric distribution.
+type GeometricParams struct {
+	// log(1-p)
+	logq float64
+}
+
+// GeometricP returns GeometricParams for the number of failures before the
+// first success in trials that each succeed with probability p.  The support
+// is therefore {0, 1, 2, ...}; add one for the number of trials.  It panics if
+// p is not in (0, 1].
+func GeometricP(p float64) *GeometricParams {
+	if !(p > 0 && p <= 1) {
+		panic("guacamole: invalid geometric probability")
+	}
+	return &GeometricParams{logq: math.Log1p(-p)}
+}
+
+// Geometric returns a sample from the provided GeometricParams.
+func (g *Guacamole) Geometric(gp *GeometricParams) uint64 {
+	checkVersions(g.version, Version1)
+	k := math.Floor(math.Log(g.openFloat64()) / gp.logq)
+	if !(k < math.MaxUint64) {
+		return math.MaxUint64
+	}
+	return uint64(k)
+}
+
+// NegativeBinomialParams specify a negative binomial distribution.
+type NegativeBinomialParams struct {
+	// zero when p is one
+	gamma *GammaParams
+}
+
+// NegativeBinomialRP returns NegativeBinomialParams for the number of failures
+// before the r'th success in trials that each succeed with probability p.  The
+// number of successes r need not be an integer.  It panics if r is not positive
+// or p is not in (0, 1].
+func NegativeBinomialRP(r, p float64) *NegativeBinomialParams {
+	if !(r > 0) || !(p > 0 && p <= 1) {
+		panic("guacamole: invalid negative binomial parameters")
+	}
+	if p == 1 {
+		return &NegativeBinomialParams{}
+	}
+	return &NegativeBinomialParams{gamma: GammaShapeScale(r, (1-p)/p)}
+}
+
+// NegativeBinomial returns a sample from the provided NegativeBinomialParams.
+// It draws lambda from a gamma distribution and then returns a draw from a
+// Poisson distribution with mean lambda.
+func (g *Guacamole) NegativeBinomial(np *NegativeBinomialParams) uint64 {
+	checkVersions(g.version, Version1)
+	if np.gamma == nil {
+		return 0
+	}
+	var pp PoissonParams
+	pp.init(g.gamma(np.gamma) * np.gamma.scale)
+	return g.poisson(&pp)
+}
+
+// HypergeometricParams specify a hypergeometric distribution.
+type HypergeometricParams struct {
+	good   uint64
+	bad    uint64
+	sample uint64
+	// the smaller of sample and good+bad-sample
+	computed uint64
+	// constants for HRUA
+	hrua       bool
+	mingoodbad float64
+	maxgoodbad float64
+	a          float64
+	h          float64
+	g          float64
+	b          float64
+}
+
+// hypergeometricThreshold is the number of draws at which Hypergeometric
+// switches to HRUA.
+const hypergeometricThreshold = 10
+
+// HypergeometricPopulation returns HypergeometricParams for the number of good
+// items in a sample drawn without replacement from a population of good+bad
+// items.  It panics if sample exceeds the population.
+func HypergeometricPopulation(good, bad, sample uint64) *HypergeometricParams {
+	total := good + bad
+	if total < good || sample > total {
+		panic("guacamole: invalid hypergeometric parameters")
+	}
+	hp := &HypergeometricParams{good: good, bad: bad, sample: sample}
+	hp.computed = sample
+	if total-sample < sample {
+		hp.computed = total - sample
+	}
+	if hp.computed < hypergeometricThreshold {
+		return hp
+	}
+	const d1 = 1.7155277699214135
+	const d2 = 0.8989161620588988
+	popsize := float64(total)
+	computed := float64(hp.computed)
+	hp.hrua = true
+	hp.mingoodbad = float64(good)
+	hp.maxgoodbad = float64(bad)
+	if good > bad {
+		hp.mingoodbad, hp.maxgoodbad = hp.maxgoodbad, hp.mingoodbad
+	}
+	p := hp.mingoodbad / popsize
+	q := hp.maxgoodbad / popsize
+	mu := computed * p
+	hp.a = mu + 0.5
+	variance := (popsize - computed) * computed * p * q / (popsize - 1)
+	c := math.Sqrt(variance + 0.5)
+	hp.h = d1*c + d2
+	m := math.Floor((computed + 1) * (hp.mingoodbad + 1) / (popsize + 2))
+	hp.g = logFactorial(m) + logFactorial(hp.mingoodbad-m) +
+		logFactorial(computed-m) + logFactorial(hp.maxgoodbad-computed+m)
+	hp.b = math.Min(math.Min(computed, hp.mingoodbad)+1, math.Floor(hp.a+16*c))
+	return hp
+}
+
+// Hypergeometric returns a sample from the provided HypergeometricParams.
+func (g *Guacamole) Hypergeometric(hp *HypergeometricParams) uint64 {
+	checkVersions(g.version, Version1)
+	if !hp.hrua {
+		return g.hypergeometricSample(hp)
+	}
+	computed := float64(hp.computed)
+	var k float64
+	for {
+		u := g.Float64()
+		v := g.Float64()
+		x := hp.a + hp.h*(v-0.5)/u
+		// fast rejection
+		if x < 0 || x >= hp.b {
+			continue
+		}
+		k = math.Floor(x)
+		t := hp.g - (logFactorial(k) + logFactorial(hp.mingoodbad-k) +
+			logFactorial(computed-k) + logFactorial(hp.maxgoodbad-computed+k))
+		// fast acceptance
+		if u*(4-u)-3 <= t {
+			break
+		}
+		// fast rejection
+		if u*(u-t) >= 1 {
+			continue
+		}
+		if 2*math.Log(u) <= t {
+			break
+		}
+	}
+	K := uint64(k)
+	if hp.good > hp.bad {
+		K = hp.computed - K
+	}
+	if hp.computed < hp.sample {
+		K = hp.good - K
+	}
+	return K
+}
+
+// hypergeometricSample draws the items of the sample one at a time.  It draws
+// the complement of the sample when that is smaller.
+func (g *Guacamole) hypergeometricSample(hp *HypergeometricParams) uint64 {
+	remaining := hp.good + hp.bad
+	remainingGood := hp.good
+	for n := hp.computed; n > 0 && remainingGood > 0; n-- {
+		if g.uint64n(remaining) < remainingGood {
+			remainingGood--
+		}
+		remaining--
+	}
+	if hp.computed < hp.sample {
+		return remainingGood
+	}
+	return hp.good - remainingGood
+}
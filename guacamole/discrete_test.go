@@ -0,0 +1,265 @@
+package guacamole_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"hack.systems/random/guacamole"
+)
+
+const discreteSamples = 200000
+
+func logChoose(n, k float64) float64 {
+	a, _ := math.Lgamma(n + 1)
+	b, _ := math.Lgamma(k + 1)
+	c, _ := math.Lgamma(n - k + 1)
+	return a - b - c
+}
+
+// checkDiscrete draws samples and performs a chi-square goodness of fit test
+// against pmf.  Values are binned individually from lo up to the largest value
+// drawn, and adjacent bins are merged until each expects at least 5 samples.
+// The last bin absorbs whatever remains of the support.
+func checkDiscrete(t *testing.T, name string, draw func(g *guacamole.Guacamole) uint64, pmf func(uint64) float64, lo uint64) {
+	require := require.New(t)
+	g := guacamole.New()
+	g.Seed(0xbadcafe)
+	counts := make(map[uint64]int)
+	hi := lo
+	for i := 0; i < discreteSamples; i++ {
+		x := draw(g)
+		require.True(x >= lo, "%s: %d is below the support", name, x)
+		counts[x]++
+		if x > hi {
+			hi = x
+		}
+	}
+
+	n := float64(discreteSamples)
+	var expected []float64
+	var observed []float64
+	total := 0.0
+	e, o := 0.0, 0.0
+	for k := lo; k <= hi; k++ {
+		e += pmf(k) * n
+		o += float64(counts[k])
+		if e >= 5 {
+			expected = append(expected, e)
+			observed = append(observed, o)
+			total += e
+			e, o = 0, 0
+		}
+	}
+	require.NotEmpty(expected, "%s: degenerate distribution", name)
+	expected[len(expected)-1] += n - total
+	observed[len(observed)-1] += o
+
+	chi2 := 0.0
+	for i := range expected {
+		d := observed[i] - expected[i]
+		chi2 += d * d / expected[i]
+	}
+	df := float64(len(expected) - 1)
+	if df < 1 {
+		return
+	}
+	// the Wilson-Hilferty approximation to the 99.9th percentile
+	h := 2 / (9 * df)
+	critical := df * math.Pow(1-h+3.09*math.Sqrt(h), 3)
+	require.True(chi2 < critical, "%s: chi2=%g with %g degrees of freedom (critical %g)", name, chi2, df, critical)
+}
+
+func TestPoisson(t *testing.T) {
+	for _, lambda := range []float64{0.5, 3, 9.9, 10, 42, 1000, 1e6} {
+		pp := guacamole.PoissonLambda(lambda)
+		checkDiscrete(t, "poisson", func(g *guacamole.Guacamole) uint64 {
+			return g.Poisson(pp)
+		}, func(k uint64) float64 {
+			lg, _ := math.Lgamma(float64(k) + 1)
+			return math.Exp(float64(k)*math.Log(lambda) - lambda - lg)
+		}, 0)
+	}
+	g := guacamole.New()
+	require.Equal(t, uint64(0), g.Poisson(guacamole.PoissonLambda(0)))
+}
+
+func TestBinomial(t *testing.T) {
+	type parameter struct {
+		n uint64
+		p float64
+	}
+	// both inversion and BTPE, and both sides of p = 0.5
+	parameters := []parameter{
+		{10, 0.3},
+		{100, 0.1},
+		{100, 0.9},
+		{100, 0.5},
+		{1000, 0.2},
+		{1000, 0.75},
+		{1000000, 0.01},
+		{1 << 40, 1e-6},
+	}
+	for _, p := range parameters {
+		bp := guacamole.BinomialNP(p.n, p.p)
+		checkDiscrete(t, "binomial", func(g *guacamole.Guacamole) uint64 {
+			return g.Binomial(bp)
+		}, func(k uint64) float64 {
+			if k > p.n {
+				return 0
+			}
+			n := float64(p.n)
+			x := float64(k)
+			return math.Exp(logChoose(n, x) + x*math.Log(p.p) + (n-x)*math.Log1p(-p.p))
+		}, 0)
+	}
+	g := guacamole.New()
+	require.Equal(t, uint64(0), g.Binomial(guacamole.BinomialNP(10, 0)))
+	require.Equal(t, uint64(10), g.Binomial(guacamole.BinomialNP(10, 1)))
+	require.Equal(t, uint64(0), g.Binomial(guacamole.BinomialNP(0, 0.5)))
+}
+
+func TestGeometric(t *testing.T) {
+	for _, p := range []float64{0.9, 0.5, 0.01} {
+		gp := guacamole.GeometricP(p)
+		checkDiscrete(t, "geometric", func(g *guacamole.Guacamole) uint64 {
+			return g.Geometric(gp)
+		}, func(k uint64) float64 {
+			return p * math.Pow(1-p, float64(k))
+		}, 0)
+	}
+	g := guacamole.New()
+	require.Equal(t, uint64(0), g.Geometric(guacamole.GeometricP(1)))
+}
+
+func TestNegativeBinomial(t *testing.T) {
+	type parameter struct {
+		r float64
+		p float64
+	}
+	for _, p := range []parameter{{1, 0.3}, {5, 0.5}, {2.5, 0.1}, {100, 0.9}} {
+		np := guacamole.NegativeBinomialRP(p.r, p.p)
+		checkDiscrete(t, "negative binomial", func(g *guacamole.Guacamole) uint64 {
+			return g.NegativeBinomial(np)
+		}, func(k uint64) float64 {
+			x := float64(k)
+			a, _ := math.Lgamma(x + p.r)
+			b, _ := math.Lgamma(p.r)
+			c, _ := math.Lgamma(x + 1)
+			return math.Exp(a - b - c + p.r*math.Log(p.p) + x*math.Log1p(-p.p))
+		}, 0)
+	}
+	g := guacamole.New()
+	require.Equal(t, uint64(0), g.NegativeBinomial(guacamole.NegativeBinomialRP(3, 1)))
+}
+
+func TestHypergeometric(t *testing.T) {
+	type parameter struct {
+		good   uint64
+		bad    uint64
+		sample uint64
+	}
+	// both the simple method and HRUA, including complemented samples and
+	// more good than bad
+	parameters := []parameter{
+		{10, 20, 5},
+		{20, 10, 25},
+		{50, 50, 9},
+		{50, 50, 10},
+		{100, 1000, 200},
+		{1000, 100, 900},
+		{1000000, 2000000, 50000},
+	}
+	for _, p := range parameters {
+		hp := guacamole.HypergeometricPopulation(p.good, p.bad, p.sample)
+		lo := uint64(0)
+		if p.sample > p.bad {
+			lo = p.sample - p.bad
+		}
+		checkDiscrete(t, "hypergeometric", func(g *guacamole.Guacamole) uint64 {
+			return g.Hypergeometric(hp)
+		}, func(k uint64) float64 {
+			if k > p.good || k > p.sample || p.sample-k > p.bad {
+				return 0
+			}
+			good, bad, sample, x := float64(p.good), float64(p.bad), float64(p.sample), float64(k)
+			return math.Exp(logChoose(good, x) + logChoose(bad, sample-x) - logChoose(good+bad, sample))
+		}, lo)
+	}
+}
+
+// TestDiscreteConsumption checks the consumption documented in discrete.go.
+func TestDiscreteConsumption(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+
+	pp := guacamole.PoissonLambda(4)
+	gp := guacamole.GeometricP(0.25)
+	for seed := uint64(0); seed < 100; seed++ {
+		g.Seed(seed)
+		k := g.Poisson(pp)
+		_, offset := g.Position()
+		require.Equal(8*(k+1), offset)
+
+		g.Seed(seed)
+		g.Geometric(gp)
+		_, offset = g.Position()
+		require.Equal(uint64(8), offset)
+	}
+
+	// every sampler is a function of the stream
+	bp := guacamole.BinomialNP(5000, 0.3)
+	hp := guacamole.HypergeometricPopulation(500, 700, 300)
+	g.Seed(7)
+	expected := make([]uint64, 0, 2000)
+	for i := 0; i < 1000; i++ {
+		expected = append(expected, g.Binomial(bp), g.Hypergeometric(hp))
+	}
+	g.Seed(7)
+	for i := 0; i < 1000; i++ {
+		require.Equal(expected[2*i], g.Binomial(bp))
+		require.Equal(expected[2*i+1], g.Hypergeometric(hp))
+	}
+}
+
+func TestDiscreteInvalid(t *testing.T) {
+	require := require.New(t)
+	require.Panics(func() { guacamole.PoissonLambda(-1) })
+	require.Panics(func() { guacamole.BinomialNP(10, 1.5) })
+	require.Panics(func() { guacamole.GeometricP(0) })
+	require.Panics(func() { guacamole.NegativeBinomialRP(0, 0.5) })
+	require.Panics(func() { guacamole.HypergeometricPopulation(1, 1, 3) })
+}
+
+func BenchmarkPoissonSmall(b *testing.B) {
+	pp := guacamole.PoissonLambda(5)
+	g := guacamole.New()
+	for n := 0; n < b.N; n++ {
+		g.Poisson(pp)
+	}
+}
+
+func BenchmarkPoissonLarge(b *testing.B) {
+	pp := guacamole.PoissonLambda(1000)
+	g := guacamole.New()
+	for n := 0; n < b.N; n++ {
+		g.Poisson(pp)
+	}
+}
+
+func BenchmarkBinomialBTPE(b *testing.B) {
+	bp := guacamole.BinomialNP(100000, 0.3)
+	g := guacamole.New()
+	for n := 0; n < b.N; n++ {
+		g.Binomial(bp)
+	}
+}
+
+func BenchmarkHypergeometricHRUA(b *testing.B) {
+	hp := guacamole.HypergeometricPopulation(100000, 200000, 5000)
+	g := guacamole.New()
+	for n := 0; n < b.N; n++ {
+		g.Hypergeometric(hp)
+	}
+}
@@ -0,0 +1,91 @@
+package guacamole_test
+
+import (
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/require"
+
+	"hack.systems/random/guacamole"
+)
+
+func TestDomainScramblerPermutation(t *testing.T) {
+	require := require.New(t)
+	for n := uint64(1); n <= 300; n++ {
+		for _, bijection := range []uint64{0, 1, 1337} {
+			d := guacamole.NewDomainScrambler(n, bijection)
+			require.Equal(n, d.N())
+			seen := make([]bool, n)
+			for x := uint64(0); x < n; x++ {
+				y := d.Scramble(x)
+				require.True(y < n, "n=%d x=%d y=%d", n, x, y)
+				require.False(seen[y], "n=%d: %d is produced twice", n, y)
+				seen[y] = true
+				require.Equal(x, d.Unscramble(y))
+			}
+		}
+	}
+	// a larger domain that is not a power of two
+	const n = 1000003
+	d := guacamole.NewDomainScrambler(n, 42)
+	seen := make([]bool, n)
+	fixed := 0
+	for x := uint64(0); x < n; x++ {
+		y := d.Scramble(x)
+		require.False(seen[y])
+		seen[y] = true
+		if x == y {
+			fixed++
+		}
+	}
+	// a random permutation has one fixed point on average
+	require.True(fixed < 10)
+}
+
+func TestDomainScramblerBijections(t *testing.T) {
+	require := require.New(t)
+	a := guacamole.NewDomainScrambler(1000, 1)
+	b := guacamole.NewDomainScrambler(1000, 2)
+	same := 0
+	for x := uint64(0); x < 1000; x++ {
+		if a.Scramble(x) == b.Scramble(x) {
+			same++
+		}
+	}
+	require.True(same < 10)
+}
+
+func TestDomainScramblerLarge(t *testing.T) {
+	require := require.New(t)
+	roundTrip := func(n, bijection, x uint64) bool {
+		if n == 0 {
+			n = 1
+		}
+		x %= n
+		d := guacamole.NewDomainScrambler(n, bijection)
+		y := d.Scramble(x)
+		return y < n && d.Unscramble(y) == x
+	}
+	config := &quick.Config{
+		MaxCount: 200,
+		Rand:     rand.New(guacamole.NewSource(0)),
+	}
+	require.NoError(quick.Check(roundTrip, config))
+	for _, n := range []uint64{1 << 62, 1<<62 + 1, 1 << 63, 1<<64 - 1} {
+		require.True(roundTrip(n, 7, 0))
+		require.True(roundTrip(n, 7, n-1))
+	}
+	require.Panics(func() { guacamole.NewDomainScrambler(0, 0) })
+	require.Panics(func() { guacamole.NewDomainScrambler(10, 0).Scramble(10) })
+	require.Panics(func() { guacamole.NewDomainScrambler(10, 0).Unscramble(10) })
+}
+
+func BenchmarkDomainScrambler(b *testing.B) {
+	d := guacamole.NewDomainScrambler(1000000000, 0)
+	sum := uint64(0)
+	for n := 0; n < b.N; n++ {
+		sum += d.Scramble(uint64(n) % 1000000000)
+	}
+	result = sum
+}
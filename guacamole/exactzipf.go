@@ -0,0 +1,174 @@
+package guacamole
+
+import (
+	"math"
+	"math/bits"
+)
+
+// ExactZipfParams specify a set of elements and the parameters to select them
+// according to a Zipf distribution in which element k is chosen with
+// probability proportional to 1/k^theta.  Unlike ZipfParams, which uses Gray's
+// approximation, the sampler is exact and will produce every element of
+// [1, N].  Construction is O(1) and each draw takes O(1) expected time for any
+// N and any theta >= 0, including theta > 1.
+//
+// The sampler is the rejection-inversion method from "Rejection-inversion to
+// generate variates from monotone discrete distributions" by Hörmann and
+// Derflinger, ACM TOMACS 1996.  Each attempt consumes one Float64, and about
+// 1-2% of attempts are rejected for theta near one; fewer for other theta.
+//
+// A Float64 has 53 bits of precision, so for large N the inversion cannot
+// land on every integer:  both the uniform and the float64 result are spaced
+// more than one apart.  Where that happens, the integers are grouped into
+// aligned blocks many times wider than that spacing, and the inversion chooses
+// a block.  The blocks partition [1, N], so every element is produced and each
+// block is chosen with its exact probability to within the error of the
+// inversion at its two ends.  A block may span as much as [2^e, 2^(e+1)),
+// across which the probability falls by as much as 2^theta, so the sampler
+// chooses within it by a second rejection step:  it draws an element uniformly
+// and accepts it with probability h(k)/h(start), where start is the first
+// element of the block.  Each step draws one Uint64 and one Float64, and on
+// average the step runs at most (1+width/(2*start))^theta times, which is
+// below 1.5^theta; blocks wide enough for that to matter are reached rarely,
+// so the expected time of a draw remains O(1).
+type ExactZipfParams struct {
+	n     uint64
+	theta float64
+	// hIntegral(1.5) - 1
+	hIntegralX1 float64
+	// hIntegral(n + 0.5)
+	hIntegralN float64
+	// the squeeze constant
+	s float64
+	// the spacing of the values the uniform variate may take
+	du float64
+	// block[e] is log2 of the size of the blocks in [2^e, 2^(e+1))
+	block [64]uint8
+}
+
+// exactZipfBlockMargin is log2 of how much wider than the spacing of the
+// inversion the blocks are.  A block's probability is off by about the
+// spacing divided by its width.
+const exactZipfBlockMargin = 10
+
+// ExactZipfTheta returns ExactZipfParams to draw from n elements with the
+// provided theta parameter.  It panics if n is zero or theta is negative.
+func ExactZipfTheta(n uint64, theta float64) *ExactZipfParams {
+	if n == 0 || !(theta >= 0) || math.IsInf(theta, 1) {
+		panic("guacamole: invalid exact Zipf parameters")
+	}
+	zp := &ExactZipfParams{n: n, theta: theta}
+	zp.hIntegralX1 = zp.hIntegral(1.5) - 1
+	zp.hIntegralN = zp.hIntegral(float64(n) + 0.5)
+	zp.s = 2 - zp.hIntegralInverse(zp.hIntegral(2.5)-zp.h(2))
+	zp.du = math.Max((zp.hIntegralN-zp.hIntegralX1)/(1<<53),
+		math.Nextafter(zp.hIntegralN, math.Inf(1))-zp.hIntegralN)
+	for e := range zp.block {
+		x := math.Ldexp(1, e+1)
+		// the spacing of the inversion at the top of the range, plus a
+		// generous bound on the rounding error of hIntegralInverse
+		spacing := zp.du/zp.h(x) + x*(1+math.Log(x))*0x1p-51
+		if spacing > 1 {
+			zp.block[e] = uint8(math.Min(float64(e), math.Ceil(math.Log2(spacing))+exactZipfBlockMargin))
+		}
+	}
+	return zp
+}
+
+// ExactZipfAlpha returns ExactZipfParams to draw from n elements with the
+// provided alpha parameter, where theta = 1 - 1/alpha as for ZipfAlpha.
+func ExactZipfAlpha(n uint64, alpha float64) *ExactZipfParams {
+	return ExactZipfTheta(n, 1-1/alpha)
+}
+
+// N specifies the number of elements in the set from which values are selected.
+func (zp *ExactZipfParams) N() uint64 {
+	return zp.n
+}
+
+// Theta returns the exponent of the distribution.
+func (zp *ExactZipfParams) Theta() float64 {
+	return zp.theta
+}
+
+// ExactZipf returns an element from the provided ExactZipfParams.  The return
+// value will be in the range [1, N].
+func (g *Guacamole) ExactZipf(zp *ExactZipfParams) uint64 {
+	checkVersions(g.version, Version1)
+	n := float64(zp.n)
+	for {
+		u := zp.hIntegralN + g.Float64()*(zp.hIntegralX1-zp.hIntegralN)
+		x := zp.hIntegralInverse(u)
+		kf := math.Round(x)
+		if kf < 1 {
+			kf = 1
+		} else if kf > n {
+			kf = n
+		}
+		if kf-x <= zp.s || u >= zp.hIntegral(kf+0.5)-zp.h(kf) {
+			return zp.widen(g, kf)
+		}
+	}
+}
+
+// widen converts the accepted element to an integer.  Where the inversion can
+// resolve individual integers this is exact.  Elsewhere, kf stands for every
+// integer in its block, and one is chosen in proportion to h.  The inversion
+// never exceeds N+0.5, so the probability of the block that contains N covers
+// only the elements up to N, and the choice is limited to them.
+func (zp *ExactZipfParams) widen(g *Guacamole, kf float64) uint64 {
+	k := zp.n
+	if kf < float64(zp.n) {
+		k = uint64(kf)
+	}
+	b := zp.block[bits.Len64(k)-1]
+	if b == 0 {
+		return k
+	}
+	start := k >> b << b
+	size := min(1<<b, zp.n-start+1)
+	for {
+		k = start + g.uint64n(size)
+		if g.Float64() < zp.h(float64(k)/float64(start)) {
+			return k
+		}
+	}
+}
+
+// h is the unnormalized probability of x:  x^-theta.
+func (zp *ExactZipfParams) h(x float64) float64 {
+	return math.Exp(-zp.theta * math.Log(x))
+}
+
+// hIntegral is the integral of h from 1 to x, written so that it remains
+// accurate as theta approaches one.
+func (zp *ExactZipfParams) hIntegral(x float64) float64 {
+	logX := math.Log(x)
+	return zipfHelper2((1-zp.theta)*logX) * logX
+}
+
+// hIntegralInverse is the inverse of hIntegral.
+func (zp *ExactZipfParams) hIntegralInverse(x float64) float64 {
+	t := x * (1 - zp.theta)
+	if t < -1 {
+		// limit the domain to avoid NaN from rounding error
+		t = -1
+	}
+	return math.Exp(zipfHelper1(t) * x)
+}
+
+// zipfHelper1 returns log(1+x)/x, and 1 when x is 0.
+func zipfHelper1(x float64) float64 {
+	if math.Abs(x) > 1e-8 {
+		return math.Log1p(x) / x
+	}
+	return 1 - x*(0.5-x*(1.0/3.0-0.25*x))
+}
+
+// zipfHelper2 returns (exp(x)-1)/x, and 1 when x is 0.
+func zipfHelper2(x float64) float64 {
+	if math.Abs(x) > 1e-8 {
+		return math.Expm1(x) / x
+	}
+	return 1 + x*0.5*(1+x*(1.0/3.0)*(1+0.25*x))
+}
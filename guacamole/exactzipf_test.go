@@ -0,0 +1,259 @@
+package guacamole_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"hack.systems/random/guacamole"
+)
+
+func exactZipfPMF(n uint64, theta float64) func(uint64) float64 {
+	zeta := 0.0
+	for k := n; k >= 1; k-- {
+		zeta += math.Pow(float64(k), -theta)
+	}
+	return func(k uint64) float64 {
+		if k < 1 || k > n {
+			return 0
+		}
+		return math.Pow(float64(k), -theta) / zeta
+	}
+}
+
+func TestExactZipf(t *testing.T) {
+	type parameter struct {
+		n     uint64
+		theta float64
+	}
+	parameters := []parameter{
+		{1, 0.5},
+		{2, 0.99},
+		{10, 0},
+		{100, 0.5},
+		{1000, 0.99},
+		{1000, 1},
+		{1000, 1.01},
+		{10000, 1.5},
+		{100000, 0.8},
+	}
+	for _, p := range parameters {
+		zp := guacamole.ExactZipfTheta(p.n, p.theta)
+		require.Equal(t, p.n, zp.N())
+		require.Equal(t, p.theta, zp.Theta())
+		checkDiscrete(t, "exact zipf", func(g *guacamole.Guacamole) uint64 {
+			return g.ExactZipf(zp)
+		}, exactZipfPMF(p.n, p.theta), 1)
+	}
+}
+
+// TestExactZipfTail checks that the elements Gray's approximation skips are
+// produced, and in the right proportion.
+func TestExactZipfTail(t *testing.T) {
+	require := require.New(t)
+	const n = 1000
+	const theta = 0.99
+	const draws = 1000000
+	pmf := exactZipfPMF(n, theta)
+	zp := guacamole.ExactZipfTheta(n, theta)
+	g := guacamole.New()
+	counts := make([]int, n+1)
+	for i := 0; i < draws; i++ {
+		counts[g.ExactZipf(zp)]++
+	}
+	// the last 1% of the support
+	tail := 0
+	expected := 0.0
+	for k := uint64(n - n/100 + 1); k <= n; k++ {
+		require.True(counts[k] > 0, "element %d was never drawn", k)
+		tail += counts[k]
+		expected += pmf(k) * draws
+	}
+	require.InDelta(expected, float64(tail), 5*math.Sqrt(expected))
+}
+
+func TestExactZipfHuge(t *testing.T) {
+	require := require.New(t)
+	start := time.Now()
+	const n = 1e16
+	for _, theta := range []float64{0.2, 0.83, 0.99, 1, 1.2, 3} {
+		zp := guacamole.ExactZipfTheta(n, theta)
+		g := guacamole.New()
+		ones := 0
+		for i := 0; i < 10000; i++ {
+			x := g.ExactZipf(zp)
+			require.True(x >= 1 && x <= n)
+			if x == 1 {
+				ones++
+			}
+		}
+		if theta >= 1.2 {
+			require.True(ones > 0, "theta=%g", theta)
+		}
+	}
+	require.True(time.Since(start) < 10*time.Second)
+
+	// with theta zero the distribution is uniform, but a Float64 alone
+	// cannot produce every integer in the range
+	zp := guacamole.ExactZipfTheta(1<<54, 0)
+	g := guacamole.New()
+	const draws = 80000
+	buckets := make([]int, 8)
+	odd := 0
+	for i := 0; i < draws; i++ {
+		x := g.ExactZipf(zp)
+		buckets[(x-1)>>51]++
+		odd += int(x & 1)
+	}
+	for _, b := range buckets {
+		require.InDelta(draws/8, b, 5*math.Sqrt(draws/8))
+	}
+	require.InDelta(draws/2, odd, 5*math.Sqrt(draws/4))
+}
+
+func TestExactZipfAlpha(t *testing.T) {
+	require := require.New(t)
+	zp := guacamole.ExactZipfAlpha(1000, 10)
+	require.InDelta(0.9, zp.Theta(), 1e-15)
+	require.Panics(func() { guacamole.ExactZipfTheta(0, 0.5) })
+	require.Panics(func() { guacamole.ExactZipfTheta(10, -1) })
+	require.Panics(func() { guacamole.ExactZipfTheta(10, math.NaN()) })
+}
+
+func BenchmarkExactZipf(b *testing.B) {
+	zp := guacamole.ExactZipfTheta(1e16, 0.99)
+	g := guacamole.New()
+	for n := 0; n < b.N; n++ {
+		g.ExactZipf(zp)
+	}
+}
+
+// TestExactZipfNearN checks the elements at the top of sets too large for a
+// Float64 to resolve, where each draw chooses among a window of integers.  The
+// top quarter of the set is divided into buckets whose expected counts come
+// from the integral of k^-theta, and the low digits of the draws from the top
+// quarter must be uniform, as they would be if no integers were skipped.
+func TestExactZipfNearN(t *testing.T) {
+	type parameter struct {
+		n     uint64
+		theta float64
+	}
+	parameters := []parameter{
+		{3 << 52, 0},
+		{1<<60 + 12345, 0},
+		{1<<60 + 12345, 0.5},
+		{1<<63 - 1, 0.7},
+	}
+	for _, p := range parameters {
+		zp := guacamole.ExactZipfTheta(p.n, p.theta)
+		hIntegral := func(x float64) float64 {
+			return (math.Pow(x, 1-p.theta) - 1) / (1 - p.theta)
+		}
+		total := hIntegral(float64(p.n)+0.5) - hIntegral(1.5) + 1
+		const buckets = 32
+		width := p.n / 4 / buckets
+		bottom := p.n - buckets*width
+		// bucket i+1 holds [bottom+i*width+1, bottom+(i+1)*width]; bucket
+		// zero holds everything below, and the last bucket ends at n
+		checkDiscrete(t, "exact zipf near n", func(g *guacamole.Guacamole) uint64 {
+			k := g.ExactZipf(zp)
+			require.True(t, k >= 1 && k <= p.n, "%d is outside [1, %d]", k, p.n)
+			if k <= bottom {
+				return 0
+			}
+			return 1 + (k-bottom-1)/width
+		}, func(i uint64) float64 {
+			if i == 0 {
+				return (hIntegral(float64(bottom)+0.5) - hIntegral(1.5) + 1) / total
+			}
+			lo := float64(bottom + (i-1)*width)
+			return (hIntegral(lo+float64(width)+0.5) - hIntegral(lo+0.5)) / total
+		}, 0)
+		checkDiscrete(t, "exact zipf low digits", func(g *guacamole.Guacamole) uint64 {
+			for {
+				if k := g.ExactZipf(zp); k > bottom {
+					return k % 60
+				}
+			}
+		}, func(uint64) float64 {
+			return 1.0 / 60
+		}, 0)
+	}
+}
+
+// TestExactZipfWidenNearN checks that the block holding N neither extends past
+// N nor favors N over its neighbors.
+func TestExactZipfWidenNearN(t *testing.T) {
+	require := require.New(t)
+	for _, theta := range []float64{0, 0.5, 0.99, 1.5} {
+		for _, n := range []uint64{3<<52 + 12345, 1<<60 + 12345, 1<<64 - 1} {
+			zp := guacamole.ExactZipfTheta(n, theta)
+			start, size := guacamole.ExactZipfBlock(zp, n)
+			require.True(size > 1, "n=%d theta=%g", n, theta)
+			require.Equal(n, start+size-1)
+			const buckets = 64
+			// the first two buckets hold n and n-1 alone
+			checkDiscrete(t, "exact zipf block", func(g *guacamole.Guacamole) uint64 {
+				k := guacamole.ExactZipfWiden(zp, g, n)
+				require.True(k >= start && k <= n, "%d is outside [%d, %d]", k, start, n)
+				if d := n - k; d < 2 {
+					return d
+				}
+				return 2 + min(buckets-1, uint64(float64(k-start)/float64(size-2)*buckets))
+			}, func(i uint64) float64 {
+				if i < 2 {
+					return 1 / float64(size)
+				}
+				return (1 - 2/float64(size)) / buckets
+			}, 0)
+		}
+	}
+}
+
+// TestExactZipfWideBlock checks blocks that span a whole binade at large
+// theta, across which the probability of an element falls by 2^theta.  The
+// choice within such a block must follow k^-theta rather than be uniform.
+func TestExactZipfWideBlock(t *testing.T) {
+	require := require.New(t)
+	type parameter struct {
+		n     uint64
+		theta float64
+		k     uint64
+	}
+	parameters := []parameter{
+		{1e16, 3, 1 << 52},
+		{1<<64 - 1, 3, 1 << 62},
+		{1<<64 - 1, 10, 32},
+	}
+	for _, p := range parameters {
+		zp := guacamole.ExactZipfTheta(p.n, p.theta)
+		start, size := guacamole.ExactZipfBlock(zp, p.k)
+		require.Equal(p.k, start, "n=%d theta=%g", p.n, p.theta)
+		require.Equal(p.k, size, "n=%d theta=%g", p.n, p.theta)
+		hIntegral := func(x float64) float64 {
+			return math.Pow(x, 1-p.theta) / (1 - p.theta)
+		}
+		const buckets = 32
+		width := size / buckets
+		// bucket i holds [start+i*width, start+(i+1)*width)
+		checkDiscrete(t, "exact zipf wide block", func(g *guacamole.Guacamole) uint64 {
+			k := guacamole.ExactZipfWiden(zp, g, p.k)
+			require.True(k >= start && k-start < size, "%d is outside the block at %d", k, start)
+			return (k - start) / width
+		}, func(i uint64) float64 {
+			if width == 1 {
+				x := float64(start + i)
+				total := 0.0
+				for j := start; j < start+size; j++ {
+					total += math.Pow(float64(j), -p.theta)
+				}
+				return math.Pow(x, -p.theta) / total
+			}
+			lo := float64(start + i*width)
+			total := hIntegral(float64(start+size)) - hIntegral(float64(start))
+			return (hIntegral(lo+float64(width)) - hIntegral(lo)) / total
+		}, 0)
+	}
+}
@@ -0,0 +1,35 @@
+package guacamole
+
+import (
+	"math/bits"
+)
+
+// Hooks that let the external tests benchmark the native Go implementation
+// regardless of which implementation backs the package, and reach inside the
+// exact Zipf sampler.
+
+var (
+	GoDisableAssembly     = goDisableAssembly
+	GoMaybeEnableAssembly = goMaybeEnableAssembly
+	GoAssemblyAvailable   = goCPUDetect
+)
+
+// NewGoFill returns the Fill method of a native Go generator seeded at 0.
+func NewGoFill() func([]byte) {
+	g := &goGuacamole{}
+	g.seed(0)
+	return g.generate
+}
+
+// ExactZipfBlock returns the first element and the number of elements of the
+// block from which ExactZipfWiden chooses for element k.
+func ExactZipfBlock(zp *ExactZipfParams, k uint64) (start, size uint64) {
+	b := zp.block[bits.Len64(k)-1]
+	start = k >> b << b
+	return start, min(1<<b, zp.n-start+1)
+}
+
+// ExactZipfWiden converts an accepted element k to the element returned.
+func ExactZipfWiden(zp *ExactZipfParams, g *Guacamole, k uint64) uint64 {
+	return zp.widen(g, float64(k))
+}
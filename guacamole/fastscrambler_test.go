@@ -0,0 +1,97 @@
+package guacamole_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/require"
+
+	"hack.systems/random/guacamole"
+)
+
+func TestFastScrambler(t *testing.T) {
+	require := require.New(t)
+
+	s := guacamole.NewFastScrambler()
+	require.Equal(uint64(0xb9760016b941777d), s.Scramble(0))
+	require.Equal(uint64(0x4a1161511e5d6973), s.Scramble(1))
+	require.Equal(uint64(0x949ee1b4fa0d340c), s.Scramble(2))
+	require.Equal(uint64(0x56307085a9bf2870), s.Scramble(3))
+	require.Equal(uint64(0x51ffeaf047fa59ae), s.Scramble(4))
+	require.Equal(uint64(3), s.Unscramble(0x56307085a9bf2870))
+
+	s.Change(1337)
+	require.Equal(uint64(0x5bb6a3b047be8eb5), s.Scramble(0))
+	s.Change(0)
+	require.Equal(uint64(0xb9760016b941777d), s.Scramble(0))
+
+	roundTrip := func(bijection, x uint64) bool {
+		s.Change(bijection)
+		return s.Unscramble(s.Scramble(x)) == x && s.Scramble(s.Unscramble(x)) == x
+	}
+	config := &quick.Config{
+		MaxCount: 1000,
+		Rand:     rand.New(guacamole.NewSource(0)),
+	}
+	require.NoError(quick.Check(roundTrip, config))
+	for _, bijection := range []uint64{0, 1, 1<<64 - 1} {
+		for _, x := range []uint64{0, 1, 1<<32 - 1, 1 << 32, 1<<64 - 1} {
+			require.True(roundTrip(bijection, x))
+		}
+	}
+}
+
+func TestFastScramblerAvalanche(t *testing.T) {
+	require := require.New(t)
+	// flipping any input bit should flip each output bit half of the time
+	const trials = 10000
+	var flips [64][64]int
+	s := guacamole.NewFastScrambler()
+	s.Change(42)
+	g := guacamole.New()
+	for n := 0; n < trials; n++ {
+		x := g.Uint64()
+		y := s.Scramble(x)
+		for i := uint(0); i < 64; i++ {
+			d := y ^ s.Scramble(x^1<<i)
+			for j := uint(0); j < 64; j++ {
+				flips[i][j] += int(d >> j & 1)
+			}
+		}
+	}
+	// five standard deviations
+	for i := range flips {
+		for j := range flips[i] {
+			p := float64(flips[i][j]) / trials
+			require.True(math.Abs(p-0.5) < 0.025, "input bit %d flips output bit %d with p=%f", i, j, p)
+		}
+	}
+}
+
+func BenchmarkFastScramblerChange(b *testing.B) {
+	s := guacamole.NewFastScrambler()
+	for n := 0; n < b.N; n++ {
+		s.Change(uint64(n))
+	}
+	result = s.Scramble(uint64(0))
+}
+
+func BenchmarkFastScramblerScramble(b *testing.B) {
+	s := guacamole.NewFastScrambler()
+	sum := uint64(0)
+	for n := 0; n < b.N; n++ {
+		sum += s.Scramble(uint64(n))
+	}
+	result = sum
+}
+
+func BenchmarkFastScramblerUnscramble(b *testing.B) {
+	s := guacamole.NewFastScrambler()
+	sum := uint64(0)
+	for n := 0; n < b.N; n++ {
+		sum += s.Unscramble(uint64(n))
+	}
+	result = sum
+}
@@ -1,3 +1,6 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
 /* Copyright (c) 2013-2017, Robert Escriva
  * All rights reserved.
  *
@@ -34,20 +37,61 @@
 #include "guacamole.h"
 
 void guacamole_mash_c(uint64_t number, uint32_t output[16]);
+void guacamole_mash_keyed_c(const uint32_t key[8], uint64_t number, uint32_t output[16]);
 void guacamole_mash_sse41(uint64_t number, uint32_t output[16]);
+void guacamole_mash_neon(uint64_t number, uint32_t output[16]);
+size_t guacamole_mash_avx2(uint64_t number, size_t blocks, unsigned char* output);
+size_t guacamole_mash_avx512(uint64_t number, size_t blocks, unsigned char* output);
+
+void (*guacamole_mash_func)(uint64_t number, uint32_t output[16]) = guacamole_mash_c;
+
+/* the wide kernels write as many of blocks consecutive numbers (beginning at
+ * number) as they can directly to output and return how many they wrote; NULL
+ * when no wide kernel is enabled */
+size_t (*guacamole_mash_wide_func)(uint64_t number, size_t blocks, unsigned char* output) = NULL;
 
 void
 guacamole_seed(struct guacamole* g, uint64_t seed)
 {
     g->nonce = seed;
     g->index = 0;
-    guacamole_mash(g->nonce, g->buffer.u32);
+
+    if (g->keyed)
+    {
+        guacamole_mash_keyed_c(g->key, g->nonce, g->buffer.u32);
+    }
+    else
+    {
+        guacamole_mash(g->nonce, g->buffer.u32);
+    }
+}
+
+void
+guacamole_key(struct guacamole* g, const unsigned char key[32])
+{
+    unsigned index = g->index;
+    int i;
+
+    g->keyed = 0;
+
+    for (i = 0; i < 8; ++i)
+    {
+        g->key[i] = (uint32_t)key[4 * i]
+                  | (uint32_t)key[4 * i + 1] << 8
+                  | (uint32_t)key[4 * i + 2] << 16
+                  | (uint32_t)key[4 * i + 3] << 24;
+        g->keyed |= g->key[i] != 0;
+    }
+
+    guacamole_seed(g, g->nonce);
+    g->index = index;
 }
 
 void
 guacamole_generate(struct guacamole* g, void* _bytes, size_t bytes_sz)
 {
     unsigned char* bytes = (unsigned char*)_bytes;
+    size_t blocks;
     assert(g->index < 64);
 
     while (bytes_sz >= 64 - g->index)
@@ -55,6 +99,15 @@ guacamole_generate(struct guacamole* g, void* _bytes, size_t bytes_sz)
         memmove(bytes, g->buffer.byte + g->index, 64 - g->index);
         bytes += 64 - g->index;
         bytes_sz -= 64 - g->index;
+
+        if (guacamole_mash_wide_func && !g->keyed && bytes_sz >= 64)
+        {
+            blocks = guacamole_mash_wide_func(g->nonce + 1, bytes_sz / 64, bytes);
+            bytes += blocks * 64;
+            bytes_sz -= blocks * 64;
+            g->nonce += blocks;
+        }
+
         guacamole_seed(g, g->nonce + 1);
     }
 
@@ -64,8 +117,6 @@ guacamole_generate(struct guacamole* g, void* _bytes, size_t bytes_sz)
     assert(g->index < 64);
 }
 
-void (*guacamole_mash_func)(uint64_t number, uint32_t output[16]) = guacamole_mash_c;
-
 #if defined (__x86_64__)
 
 #define cpuid(a, b, c, d, inp) \
@@ -74,41 +125,117 @@ void (*guacamole_mash_func)(uint64_t number, uint32_t output[16]) = guacamole_ma
        "xchg %%rdi, %%rbx\n"   \
        : "=a" (a), "=D" (b), "=c" (c), "=d" (d) : "a" (inp))
 
-static void (*guacamole_cpu_detect(void))(uint64_t number, uint32_t output[16])
+#define cpuid_count(a, b, c, d, inp, count) \
+  asm ("mov %%rbx, %%rdi\n"    \
+       "cpuid\n"               \
+       "xchg %%rdi, %%rbx\n"   \
+       : "=a" (a), "=D" (b), "=c" (c), "=d" (d) : "a" (inp), "c" (count))
+
+static int
+guacamole_cpu_detect(void)
 {
     uint32_t eax;
     uint32_t ebx;
     uint32_t ecx;
     uint32_t edx;
+    uint32_t max_leaf;
+    uint32_t xcr0;
+    int isa = GUACAMOLE_ISA_C;
+    cpuid(max_leaf, ebx, ecx, edx, 0);
     cpuid(eax, ebx, ecx, edx, 1);
 
     if ((ecx & 0x180000))
     {
-        return guacamole_mash_sse41;
+        isa = GUACAMOLE_ISA_SSE41;
     }
 
-    return guacamole_mash_c;
+    /* the wider registers are only usable if the OS saves them (OSXSAVE) */
+    if (max_leaf < 7 || !(ecx & (1U << 27)))
+    {
+        return isa;
+    }
+
+    asm ("xgetbv" : "=a" (xcr0), "=d" (edx) : "c" (0));
+    cpuid_count(eax, ebx, ecx, edx, 7, 0);
+
+    /* XMM and YMM state, plus AVX2 */
+    if ((xcr0 & 0x6) == 0x6 && (ebx & (1U << 5)))
+    {
+        isa = GUACAMOLE_ISA_AVX2;
+    }
+
+    /* opmask and ZMM state, plus AVX-512F */
+    if ((xcr0 & 0xe6) == 0xe6 && (ebx & (1U << 16)))
+    {
+        isa = GUACAMOLE_ISA_AVX512;
+    }
+
+    return isa;
+}
+
+#elif defined (__aarch64__)
+
+static int
+guacamole_cpu_detect(void)
+{
+    /* Advanced SIMD is mandatory on AArch64 */
+    return GUACAMOLE_ISA_NEON;
 }
 
 #else
 
-static void (*guacamole_cpu_detect(void))(uint64_t number, uint32_t output[16])
+static int
+guacamole_cpu_detect(void)
 {
-    return guacamole_mash_c;
+    return GUACAMOLE_ISA_C;
 }
 
 #endif
 
+int
+guacamole_limit_assembly(int isa)
+{
+    int detected = guacamole_cpu_detect();
+
+    if (isa > detected)
+    {
+        isa = detected;
+    }
+
+    guacamole_mash_func = guacamole_mash_c;
+    guacamole_mash_wide_func = NULL;
+#if defined (__x86_64__)
+    if (isa >= GUACAMOLE_ISA_SSE41)
+    {
+        guacamole_mash_func = guacamole_mash_sse41;
+    }
+    if (isa == GUACAMOLE_ISA_AVX2)
+    {
+        guacamole_mash_wide_func = guacamole_mash_avx2;
+    }
+    if (isa >= GUACAMOLE_ISA_AVX512)
+    {
+        guacamole_mash_wide_func = guacamole_mash_avx512;
+    }
+#elif defined (__aarch64__)
+    if (isa >= GUACAMOLE_ISA_NEON)
+    {
+        guacamole_mash_func = guacamole_mash_neon;
+    }
+#endif
+    return isa;
+}
+
 void
 guacamole_disable_assembly()
 {
-    guacamole_mash_func = guacamole_mash_c;
+    guacamole_limit_assembly(GUACAMOLE_ISA_C);
 }
 
 void
 guacamole_maybe_enable_assembly()
 {
-    guacamole_mash_func = guacamole_cpu_detect();
+    guacamole_limit_assembly(GUACAMOLE_ISA_AVX512);
 }
 
 /*******************************************************************************
@@ -199,6 +326,17 @@ guacamole_double(struct guacamole* g)
     return (a * 67108864.0 + b) / 9007199254740992.0;
 }
 
+void
+guacamole_double_fill(struct guacamole* g, double* dst, size_t dst_sz)
+{
+    size_t i;
+
+    for (i = 0; i < dst_sz; ++i)
+    {
+        dst[i] = guacamole_double(g);
+    }
+}
+
 /*******************************************************************************
  * "Quickly Generating Billion-Record Synthetic Databases"
  * Gray et.al., SIGMOD 1994
@@ -291,11 +429,84 @@ static struct guacamole_zipf_params precomputed[] = {
         {1000000000000, 10000, 0.9999, 28.246438229354407, 0, 0.002690162403990004},
 };
 
+/* Below this many terms, zeta is summed directly, exactly as it always has
+ * been, so that every set that is not in the precomputed table draws the same
+ * values it always has.  It is the smallest n in the table.  Beyond, the sum is
+ * O(n) and would take hours for the largest sets, so it is replaced by
+ * zipf_harmonic. */
+#define ZIPF_ZETA_DIRECT 10000000
+
+/* Number of terms zipf_harmonic sums before switching to Euler-Maclaurin. */
+#define ZIPF_HARMONIC_DIRECT 32
+
+/* (exp(x) - 1) / x, accurate as x approaches 0 */
+static double
+zipf_expm1_ratio(double x)
+{
+    if (fabs(x) > 1e-8)
+    {
+        return expm1(x) / x;
+    }
+
+    return 1 + x * 0.5 * (1 + x * (1.0 / 3.0) * (1 + 0.25 * x));
+}
+
+/* The sum of i^-theta for i in [1, n], in O(1).  The first terms are summed
+ * directly and the rest are approximated with the Euler-Maclaurin formula
+ * through the B8 term, which leaves a relative error of about 1e-14.  This
+ * must stay in sync with zipfHarmonic in zipfstats.go. */
+double
+zipf_harmonic(uint64_t n, double theta)
+{
+    static const double coefficients[] = {1.0 / 12, -1.0 / 720, 1.0 / 30240, -1.0 / 1209600};
+    double sum = 0;
+
+    for (uint64_t i = 1; i <= n && i < ZIPF_HARMONIC_DIRECT; ++i)
+    {
+        sum += pow(i, -theta);
+    }
+
+    if (n < ZIPF_HARMONIC_DIRECT)
+    {
+        return sum;
+    }
+
+    const double a = ZIPF_HARMONIC_DIRECT;
+    const double b = n;
+    /* the integral of x^-theta from a to b */
+    const double log_ba = log(b / a);
+    sum += pow(a, 1 - theta) * log_ba * zipf_expm1_ratio((1 - theta) * log_ba);
+    /* the endpoint correction */
+    const double fa = pow(a, -theta);
+    const double fb = pow(b, -theta);
+    sum += (fa + fb) / 2;
+    /* the derivative corrections */
+    double rising = -theta;
+    double da = fa / a;
+    double db = fb / b;
+
+    for (int j = 0; j < 4; ++j)
+    {
+        sum += coefficients[j] * rising * (db - da);
+        const double d = 2 * j + 1;
+        rising *= (theta + d) * (theta + d + 1);
+        da /= a * a;
+        db /= b * b;
+    }
+
+    return sum;
+}
+
 double
 zipf_zeta(uint64_t n, double theta)
 {
     double sum = 0;
 
+    if (n >= ZIPF_ZETA_DIRECT)
+    {
+        return zipf_harmonic(n, theta);
+    }
+
     for (uint64_t i = 0; i < n; ++i)
     {
         sum += 1. / pow(i + 1, theta);
@@ -351,6 +562,18 @@ guacamole_zipf(struct guacamole* g, struct guacamole_zipf_params* p)
     return 1 + (uint64_t)(p->n * pow(p->eta * u - p->eta + 1, p->alpha));
 }
 
+void
+guacamole_zipf_fill(struct guacamole* g, struct guacamole_zipf_params* p,
+                    uint64_t* dst, size_t dst_sz)
+{
+    size_t i;
+
+    for (i = 0; i < dst_sz; ++i)
+    {
+        dst[i] = guacamole_zipf(g, p);
+    }
+}
+
 /*******************************************************************************
 Derived from salsa208, but chopped up for speed.
 Guacamole is a better dip for chips than Salsa.
@@ -459,12 +682,302 @@ guacamole_mash_c(uint64_t number, uint32_t output[16])
     QUAD_OUTPUT(output + 12, D);
 }
 
+/* guacamole_mash_c with the key in the words Salsa reserves for it; the
+ * unkeyed stream leaves them zero, which lets the compiler and the SIMD
+ * kernels fold them away */
+void
+guacamole_mash_keyed_c(const uint32_t key[8], uint64_t number, uint32_t output[16])
+{
+    uint32_t x0, x1, x2, x3, x4, x5, x6, x7, x8, x9, x10, x11, x12, x13, x14, x15;
+    int i;
+    uint32_t low = number & 0xffffffff;
+    uint32_t high = number >> 32;
+
+    x0 = 1634760805;
+    x1 = key[0];
+    x2 = key[1];
+    x3 = key[2];
+    x4 = key[3];
+    x5 = 857760878;
+    x6 = low;
+    x7 = high;
+    x8 = 0;
+    x9 = 0;
+    x10 = 2036477234;
+    x11 = key[4];
+    x12 = key[5];
+    x13 = key[6];
+    x14 = key[7];
+    x15 = 1797285236;
+
+    for (i = ROUNDS;i > 0;i -= 2)
+    {
+        QUAD_ROTATE(A, B, C, 7);
+        QUAD_ROTATE(B, C, D, 9);
+        QUAD_ROTATE(C, D, A, 13);
+        QUAD_ROTATE(D, A, B, 18);
+
+        QUAD_ROTATE(E, F, G, 7);
+        QUAD_ROTATE(F, G, D, 9);
+        QUAD_ROTATE(G, D, E, 13);
+        QUAD_ROTATE(D, E, F, 18);
+    }
+
+    x0 += 1634760805;
+    x1 += key[0];
+    x2 += key[1];
+    x3 += key[2];
+    x4 += key[3];
+    x5 += 857760878;
+    x6 += low;
+    x7 += high;
+    x10 += 2036477234;
+    x11 += key[4];
+    x12 += key[5];
+    x13 += key[6];
+    x14 += key[7];
+    x15 += 1797285236;
+
+    QUAD_OUTPUT(output, A);
+    QUAD_OUTPUT(output + 4, B);
+    QUAD_OUTPUT(output + 8, C);
+    QUAD_OUTPUT(output + 12, D);
+}
+
 void
 guacamole_mash(uint64_t number, uint32_t output[16])
 {
     guacamole_mash_func(number, output);
 }
 
+#if defined (__x86_64__)
+
+#include <immintrin.h>
+
+/* The wide kernels lay out registers the same way guacamole_mash_sse41 does:
+ * one row (A, B, C, or D) of a block per 128-bit lane.  A 256-bit register
+ * thus holds a row of two consecutive blocks and a 512-bit register holds a row
+ * of four.  Two independent groups of registers are interleaved so that each
+ * iteration computes 4 (AVX2) or 8 (AVX-512) blocks.
+ */
+#define WIDE_GROUPS 2
+
+#define WIDE_QUAD_ROTATE(ADD, XOR, ROT, x, y, z, n) \
+    x = XOR(x, ROT(ADD(y, z), n))
+
+#define WIDE_DOUBLE_ROUND(ADD, XOR, ROT, SHUF, A, B, C, D) \
+    WIDE_QUAD_ROTATE(ADD, XOR, ROT, A, B, C, 7);  \
+    WIDE_QUAD_ROTATE(ADD, XOR, ROT, B, C, D, 9);  \
+    WIDE_QUAD_ROTATE(ADD, XOR, ROT, C, D, A, 13); \
+    WIDE_QUAD_ROTATE(ADD, XOR, ROT, D, A, B, 18); \
+    A = SHUF(A, 147); /* swap A to G */           \
+    B = SHUF(B, 78);  /* swap B to F */           \
+    C = SHUF(C, 57);  /* swap C to E */           \
+    WIDE_QUAD_ROTATE(ADD, XOR, ROT, C, B, A, 7);  \
+    WIDE_QUAD_ROTATE(ADD, XOR, ROT, B, A, D, 9);  \
+    WIDE_QUAD_ROTATE(ADD, XOR, ROT, A, D, C, 13); \
+    WIDE_QUAD_ROTATE(ADD, XOR, ROT, D, C, B, 18); \
+    A = SHUF(A, 57);  /* swap G to A */           \
+    B = SHUF(B, 78);  /* swap F to B */           \
+    C = SHUF(C, 147)  /* swap E to C */
+
+#define AVX2_ROTATE(x, n) \
+    _mm256_or_si256(_mm256_slli_epi32(x, n), _mm256_srli_epi32(x, 32 - (n)))
+#define AVX2_SHUFFLE(x, imm) _mm256_shuffle_epi32(x, imm)
+
+__attribute__ ((target ("avx2")))
+size_t
+guacamole_mash_avx2(uint64_t number, size_t blocks, unsigned char* output)
+{
+    const __m256i D = _mm256_set_epi32(1797285236, 2036477234, 857760878, 1634760805,
+                                       1797285236, 2036477234, 857760878, 1634760805);
+    __m256i a[WIDE_GROUPS];
+    __m256i b[WIDE_GROUPS];
+    __m256i c[WIDE_GROUPS];
+    __m256i d[WIDE_GROUPS];
+    __m256i b_init[WIDE_GROUPS];
+    __m256i c_init[WIDE_GROUPS];
+    __m256i* out;
+    uint64_t n0;
+    uint64_t n1;
+    size_t i;
+    int g;
+    int r;
+
+    for (i = 0; i + 2 * WIDE_GROUPS <= blocks; i += 2 * WIDE_GROUPS)
+    {
+        for (g = 0; g < WIDE_GROUPS; ++g)
+        {
+            n0 = number + i + 2 * g;
+            n1 = n0 + 1;
+            a[g] = _mm256_setzero_si256();
+            b[g] = b_init[g] = _mm256_set_epi32(n1 >> 32, 0, 0, 0, n0 >> 32, 0, 0, 0);
+            c[g] = c_init[g] = _mm256_set_epi32(0, n1 & 0xffffffff, 0, 0, 0, n0 & 0xffffffff, 0, 0);
+            d[g] = D;
+        }
+
+        for (r = ROUNDS; r > 0; r -= 2)
+        {
+            for (g = 0; g < WIDE_GROUPS; ++g)
+            {
+                WIDE_DOUBLE_ROUND(_mm256_add_epi32, _mm256_xor_si256, AVX2_ROTATE, AVX2_SHUFFLE,
+                                  a[g], b[g], c[g], d[g]);
+            }
+        }
+
+        out = (__m256i*)(output + 64 * i);
+
+        for (g = 0; g < WIDE_GROUPS; ++g)
+        {
+            b[g] = _mm256_add_epi32(b[g], b_init[g]);
+            c[g] = _mm256_add_epi32(c[g], c_init[g]);
+            d[g] = _mm256_add_epi32(d[g], D);
+            /* lane 0 is the first block; lane 1 the second */
+            _mm256_storeu_si256(out + 4 * g + 0, _mm256_permute2x128_si256(a[g], b[g], 0x20));
+            _mm256_storeu_si256(out + 4 * g + 1, _mm256_permute2x128_si256(c[g], d[g], 0x20));
+            _mm256_storeu_si256(out + 4 * g + 2, _mm256_permute2x128_si256(a[g], b[g], 0x31));
+            _mm256_storeu_si256(out + 4 * g + 3, _mm256_permute2x128_si256(c[g], d[g], 0x31));
+        }
+    }
+
+    return i;
+}
+
+#define AVX512_ROTATE(x, n) _mm512_rol_epi32(x, n)
+#define AVX512_SHUFFLE(x, imm) _mm512_shuffle_epi32(x, (_MM_PERM_ENUM)(imm))
+
+__attribute__ ((target ("avx512f")))
+size_t
+guacamole_mash_avx512(uint64_t number, size_t blocks, unsigned char* output)
+{
+    const __m512i D = _mm512_set_epi32(1797285236, 2036477234, 857760878, 1634760805,
+                                       1797285236, 2036477234, 857760878, 1634760805,
+                                       1797285236, 2036477234, 857760878, 1634760805,
+                                       1797285236, 2036477234, 857760878, 1634760805);
+    __m512i a[WIDE_GROUPS];
+    __m512i b[WIDE_GROUPS];
+    __m512i c[WIDE_GROUPS];
+    __m512i d[WIDE_GROUPS];
+    __m512i b_init[WIDE_GROUPS];
+    __m512i c_init[WIDE_GROUPS];
+    __m512i lo;
+    __m512i hi;
+    __m512i* out;
+    uint64_t n0;
+    uint64_t n1;
+    uint64_t n2;
+    uint64_t n3;
+    size_t i;
+    int g;
+    int r;
+
+    for (i = 0; i + 4 * WIDE_GROUPS <= blocks; i += 4 * WIDE_GROUPS)
+    {
+        for (g = 0; g < WIDE_GROUPS; ++g)
+        {
+            n0 = number + i + 4 * g;
+            n1 = n0 + 1;
+            n2 = n0 + 2;
+            n3 = n0 + 3;
+            a[g] = _mm512_setzero_si512();
+            b[g] = b_init[g] = _mm512_set_epi32(n3 >> 32, 0, 0, 0, n2 >> 32, 0, 0, 0,
+                                                n1 >> 32, 0, 0, 0, n0 >> 32, 0, 0, 0);
+            c[g] = c_init[g] = _mm512_set_epi32(0, n3 & 0xffffffff, 0, 0, 0, n2 & 0xffffffff, 0, 0,
+                                                0, n1 & 0xffffffff, 0, 0, 0, n0 & 0xffffffff, 0, 0);
+            d[g] = D;
+        }
+
+        for (r = ROUNDS; r > 0; r -= 2)
+        {
+            for (g = 0; g < WIDE_GROUPS; ++g)
+            {
+                WIDE_DOUBLE_ROUND(_mm512_add_epi32, _mm512_xor_si512, AVX512_ROTATE, AVX512_SHUFFLE,
+                                  a[g], b[g], c[g], d[g]);
+            }
+        }
+
+        out = (__m512i*)(output + 64 * i);
+
+        for (g = 0; g < WIDE_GROUPS; ++g)
+        {
+            b[g] = _mm512_add_epi32(b[g], b_init[g]);
+            c[g] = _mm512_add_epi32(c[g], c_init[g]);
+            d[g] = _mm512_add_epi32(d[g], D);
+            /* transpose the 4x4 matrix of 128-bit lanes so that each register
+             * holds one block:  first (A0, A1, B0, B1) and (C0, C1, D0, D1) ... */
+            lo = _mm512_shuffle_i32x4(a[g], b[g], _MM_SHUFFLE(1, 0, 1, 0));
+            hi = _mm512_shuffle_i32x4(c[g], d[g], _MM_SHUFFLE(1, 0, 1, 0));
+            /* ... then (A0, B0, C0, D0) and (A1, B1, C1, D1) */
+            _mm512_storeu_si512(out + 4 * g + 0, _mm512_shuffle_i32x4(lo, hi, _MM_SHUFFLE(2, 0, 2, 0)));
+            _mm512_storeu_si512(out + 4 * g + 1, _mm512_shuffle_i32x4(lo, hi, _MM_SHUFFLE(3, 1, 3, 1)));
+            lo = _mm512_shuffle_i32x4(a[g], b[g], _MM_SHUFFLE(3, 2, 3, 2));
+            hi = _mm512_shuffle_i32x4(c[g], d[g], _MM_SHUFFLE(3, 2, 3, 2));
+            _mm512_storeu_si512(out + 4 * g + 2, _mm512_shuffle_i32x4(lo, hi, _MM_SHUFFLE(2, 0, 2, 0)));
+            _mm512_storeu_si512(out + 4 * g + 3, _mm512_shuffle_i32x4(lo, hi, _MM_SHUFFLE(3, 1, 3, 1)));
+        }
+    }
+
+    return i;
+}
+
+#endif
+
+#if defined (__aarch64__)
+
+#include <arm_neon.h>
+
+/* A translation of guacamole_mash_sse41 to Advanced SIMD.  Each of A, B, C,
+ * and D holds one row of the block, and vext rotates the lanes of a row the
+ * same way pshufd does in the SSE 4.1 implementation.
+ */
+#define NEON_QUAD_ROTATE(x, y, z, n) \
+    t = vaddq_u32(y, z); \
+    x = veorq_u32(x, vsriq_n_u32(vshlq_n_u32(t, n), t, 32 - (n)))
+
+void
+guacamole_mash_neon(uint64_t number, uint32_t output[16])
+{
+    static const uint32_t d_init[4] = {1634760805, 857760878, 2036477234, 1797285236};
+    const uint32x4_t zero = vdupq_n_u32(0);
+    const uint32x4_t b_init = vsetq_lane_u32(number >> 32, zero, 3);
+    const uint32x4_t c_init = vsetq_lane_u32(number & 0xffffffff, zero, 2);
+    const uint32x4_t d = vld1q_u32(d_init);
+    uint32x4_t A = zero;
+    uint32x4_t B = b_init;
+    uint32x4_t C = c_init;
+    uint32x4_t D = d;
+    uint32x4_t t;
+    int i;
+
+    for (i = ROUNDS; i > 0; i -= 2)
+    {
+        NEON_QUAD_ROTATE(A, B, C, 7);
+        NEON_QUAD_ROTATE(B, C, D, 9);
+        NEON_QUAD_ROTATE(C, D, A, 13);
+        NEON_QUAD_ROTATE(D, A, B, 18);
+
+        A = vextq_u32(A, A, 3); /* swap A to G */
+        B = vextq_u32(B, B, 2); /* swap B to F */
+        C = vextq_u32(C, C, 1); /* swap C to E */
+
+        NEON_QUAD_ROTATE(C, B, A, 7);  /* E ^= rotate(F + G, 7) */
+        NEON_QUAD_ROTATE(B, A, D, 9);  /* F ^= rotate(G + D, 9) */
+        NEON_QUAD_ROTATE(A, D, C, 13); /* G ^= rotate(D + E, 13) */
+        NEON_QUAD_ROTATE(D, C, B, 18); /* D ^= rotate(E + F, 18) */
+
+        A = vextq_u32(A, A, 1); /* swap G to A */
+        B = vextq_u32(B, B, 2); /* swap F to B */
+        C = vextq_u32(C, C, 3); /* swap E to C */
+    }
+
+    vst1q_u32(output + 0, A);
+    vst1q_u32(output + 4, vaddq_u32(B, b_init));
+    vst1q_u32(output + 8, vaddq_u32(C, c_init));
+    vst1q_u32(output + 12, vaddq_u32(D, d));
+}
+
+#endif
+
 /*******************************************************************************
  * Blowfish cipher copied from OpenBSD and simplified to implement scramble
  * Starting code had this license:
@@ -545,6 +1058,31 @@ Blowfish_encipher(struct guacamole_scrambler *c, uint32_t *xl, uint32_t *xr)
     *xr = Xl;
 }
 
+void
+Blowfish_decipher(struct guacamole_scrambler *c, uint32_t *xl, uint32_t *xr)
+{
+    uint32_t Xl;
+    uint32_t Xr;
+    uint32_t *s = c->S[0];
+    uint32_t *p = c->P;
+
+    Xl = *xl;
+    Xr = *xr;
+
+    Xl ^= p[17];
+    BLFRND(s, p, Xr, Xl, 16); BLFRND(s, p, Xl, Xr, 15);
+    BLFRND(s, p, Xr, Xl, 14); BLFRND(s, p, Xl, Xr, 13);
+    BLFRND(s, p, Xr, Xl, 12); BLFRND(s, p, Xl, Xr, 11);
+    BLFRND(s, p, Xr, Xl, 10); BLFRND(s, p, Xl, Xr, 9);
+    BLFRND(s, p, Xr, Xl, 8); BLFRND(s, p, Xl, Xr, 7);
+    BLFRND(s, p, Xr, Xl, 6); BLFRND(s, p, Xl, Xr, 5);
+    BLFRND(s, p, Xr, Xl, 4); BLFRND(s, p, Xl, Xr, 3);
+    BLFRND(s, p, Xr, Xl, 2); BLFRND(s, p, Xl, Xr, 1);
+
+    *xl = Xr ^ p[0];
+    *xr = Xl;
+}
+
 void
 Blowfish_initstate(struct guacamole_scrambler *c)
 {
@@ -955,3 +1493,27 @@ guacamole_scramble(struct guacamole_scrambler* gs, uint64_t value)
     x |= xr;
     return x;
 }
+
+uint64_t
+guacamole_unscramble(struct guacamole_scrambler* gs, uint64_t value)
+{
+    uint32_t xl = (value >> 32) & 0xffffffffU;
+    uint32_t xr = value & 0xffffffffU;
+    Blowfish_decipher(gs, &xl, &xr);
+    uint64_t x = xl;
+    x <<= 32;
+    x |= xr;
+    return x;
+}
+
+void
+guacamole_scramble_slice(struct guacamole_scrambler* gs, uint64_t* dst,
+                         const uint64_t* src, size_t sz)
+{
+    size_t i;
+
+    for (i = 0; i < sz; ++i)
+    {
+        dst[i] = guacamole_scramble(gs, src[i]);
+    }
+}
@@ -33,11 +33,17 @@
 #include <stdint.h>
 #include <stdlib.h>
 
-/* generate random bytes */
+/* generate random bytes
+ *
+ * the struct must be zeroed or keyed with guacamole_key before it is first
+ * seeded; a zero key selects the original, unkeyed stream
+ */
 struct guacamole
 {
     uint64_t nonce;
     unsigned index;
+    int keyed;
+    uint32_t key[8];
     union {
         uint32_t u32[16];
         uint32_t u32v[16][4];
@@ -45,9 +51,11 @@ struct guacamole
     } __attribute__ ((aligned (64))) buffer;
 };
 void guacamole_seed(struct guacamole* g, uint64_t seed);
+void guacamole_key(struct guacamole* g, const unsigned char key[32]);
 void guacamole_generate(struct guacamole* g, void* bytes, size_t bytes_sz);
 uint32_t guacamole_uint32(struct guacamole* g);
 double guacamole_double(struct guacamole* g);
+void guacamole_double_fill(struct guacamole* g, double* dst, size_t dst_sz);
 
 /* draw numbers froma  Zipf distribution with the given parameters */
 struct guacamole_zipf_params
@@ -62,6 +70,8 @@ struct guacamole_zipf_params
 void guacamole_zipf_init_alpha(uint64_t n, double alpha, struct guacamole_zipf_params* p);
 void guacamole_zipf_init_theta(uint64_t n, double theta, struct guacamole_zipf_params* p);
 uint64_t guacamole_zipf(struct guacamole* g, struct guacamole_zipf_params* p);
+void guacamole_zipf_fill(struct guacamole* g, struct guacamole_zipf_params* p,
+                         uint64_t* dst, size_t dst_sz);
 
 /* scramble the given value through the specified bijection
  * useful for turning zipf output into values spread out in space
@@ -74,6 +84,9 @@ struct guacamole_scrambler
 };
 void guacamole_scrambler_change(struct guacamole_scrambler* gs, uint64_t bijection);
 uint64_t guacamole_scramble(struct guacamole_scrambler* gs, uint64_t value);
+uint64_t guacamole_unscramble(struct guacamole_scrambler* gs, uint64_t value);
+void guacamole_scramble_slice(struct guacamole_scrambler* gs, uint64_t* dst,
+                              const uint64_t* src, size_t sz);
 
 /* low level 64-bit number to 64-byte output; safe to sequentially increment #
  *
@@ -84,4 +97,18 @@ void guacamole_mash(uint64_t number, uint32_t output[16]);
 void guacamole_disable_assembly();
 void guacamole_maybe_enable_assembly();
 
+/* enable the fastest implementation the processor supports that is no more
+ * advanced than isa; returns the isa actually enabled
+ *
+ * SSE 4.1 accelerates the mash of a single block.  AVX2 and AVX-512 add wide
+ * kernels that guacamole_generate uses to mash 4 or 8 blocks at a time.  On
+ * arm64, NEON occupies the same level as SSE 4.1.
+ */
+#define GUACAMOLE_ISA_C         0
+#define GUACAMOLE_ISA_SSE41     1
+#define GUACAMOLE_ISA_AVX2      2
+#define GUACAMOLE_ISA_AVX512    3
+#define GUACAMOLE_ISA_NEON      1
+int guacamole_limit_assembly(int isa);
+
 #endif /* guacamole_h_ */
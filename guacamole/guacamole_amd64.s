@@ -1,3 +1,6 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
 # Copyright (c) 2017-2018 Robert Escriva
 # All rights reserved.
 #
@@ -0,0 +1,136 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
+package guacamole
+
+// #cgo LDFLAGS: -lm
+// #include <errno.h>
+// #include <stdlib.h>
+// #include "guacamole.h"
+import "C"
+
+import (
+	"unsafe"
+)
+
+// This file binds the exported API to the C implementation in guacamole.c.
+// It is the default whenever cgo is available.
+
+func disableAssembly() {
+	C.guacamole_disable_assembly()
+}
+
+func maybeEnableAssembly() {
+	C.guacamole_maybe_enable_assembly()
+}
+
+// Instruction sets understood by limitAssembly.
+const (
+	isaC      = C.GUACAMOLE_ISA_C
+	isaSSE41  = C.GUACAMOLE_ISA_SSE41
+	isaAVX2   = C.GUACAMOLE_ISA_AVX2
+	isaAVX512 = C.GUACAMOLE_ISA_AVX512
+	isaNEON   = C.GUACAMOLE_ISA_NEON
+)
+
+// limitAssembly enables the fastest implementation no more advanced than isa
+// and returns the isa that was actually enabled.
+func limitAssembly(isa int) int {
+	return int(C.guacamole_limit_assembly(C.int(isa)))
+}
+
+type guacamole struct {
+	guac C.struct_guacamole
+}
+
+func (g *guacamole) seed(s uint64) {
+	C.guacamole_seed(&g.guac, C.uint64_t(s))
+}
+
+func (g *guacamole) setKey(key *[32]byte) {
+	C.guacamole_key(&g.guac, (*C.uchar)(unsafe.Pointer(&key[0])))
+}
+
+func (g *guacamole) seek(nonce uint64, index int) {
+	g.seed(nonce)
+	g.guac.index = C.unsigned(index)
+}
+
+func (g *guacamole) tell() (nonce uint64, index int) {
+	return uint64(g.guac.nonce), int(g.guac.index)
+}
+
+func (g *guacamole) generate(bytes []byte) {
+	if len(bytes) == 0 {
+		return
+	}
+	C.guacamole_generate(&g.guac, unsafe.Pointer(&bytes[0]), C.size_t(len(bytes)))
+}
+
+func (g *guacamole) double() float64 {
+	return float64(C.guacamole_double(&g.guac))
+}
+
+func (g *guacamole) doubles(dst []float64) {
+	if len(dst) == 0 {
+		return
+	}
+	C.guacamole_double_fill(&g.guac, (*C.double)(unsafe.Pointer(&dst[0])), C.size_t(len(dst)))
+}
+
+type zipfParams struct {
+	gzp C.struct_guacamole_zipf_params
+}
+
+func (z *zipfParams) initAlpha(n uint64, alpha float64) {
+	C.guacamole_zipf_init_alpha(C.uint64_t(n), C.double(alpha), &z.gzp)
+}
+
+func (z *zipfParams) initTheta(n uint64, theta float64) {
+	C.guacamole_zipf_init_theta(C.uint64_t(n), C.double(theta), &z.gzp)
+}
+
+func (z *zipfParams) dump() (n uint64, alpha, theta, zetan, zeta2, eta float64) {
+	n = uint64(z.gzp.n)
+	alpha = float64(z.gzp.alpha)
+	theta = float64(z.gzp.theta)
+	zetan = float64(z.gzp.zetan)
+	zeta2 = float64(z.gzp.zeta2)
+	eta = float64(z.gzp.eta)
+	return
+}
+
+func (z *zipfParams) draw(g *guacamole) uint64 {
+	return uint64(C.guacamole_zipf(&g.guac, &z.gzp))
+}
+
+func (z *zipfParams) fill(g *guacamole, dst []uint64) {
+	if len(dst) == 0 {
+		return
+	}
+	C.guacamole_zipf_fill(&g.guac, &z.gzp, (*C.uint64_t)(unsafe.Pointer(&dst[0])), C.size_t(len(dst)))
+}
+
+type scrambler struct {
+	scr C.struct_guacamole_scrambler
+}
+
+func (s *scrambler) change(bijection uint64) {
+	C.guacamole_scrambler_change(&s.scr, C.uint64_t(bijection))
+}
+
+func (s *scrambler) scramble(x uint64) uint64 {
+	return uint64(C.guacamole_scramble(&s.scr, C.uint64_t(x)))
+}
+
+func (s *scrambler) unscramble(x uint64) uint64 {
+	return uint64(C.guacamole_unscramble(&s.scr, C.uint64_t(x)))
+}
+
+func (s *scrambler) scrambleSlice(dst, src []uint64) {
+	if len(src) == 0 {
+		return
+	}
+	C.guacamole_scramble_slice(&s.scr, (*C.uint64_t)(unsafe.Pointer(&dst[0])),
+		(*C.uint64_t)(unsafe.Pointer(&src[0])), C.size_t(len(src)))
+}
@@ -0,0 +1,293 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
+package guacamole
+
+import (
+	"encoding/binary"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// These tests check the native Go implementation against the C implementation.
+// They only build when the C implementation backs the package.
+
+func TestGoKnownAnswers(t *testing.T) {
+	require := require.New(t)
+
+	g := &goGuacamole{}
+	g.seed(0)
+	b := make([]byte, 8)
+	g.generate(b)
+	require.Equal([]byte(first8Bytes), b)
+
+	g.seed(0)
+	require.Equal(firstDouble, g.double())
+
+	s := &goScrambler{}
+	s.change(0)
+	require.Equal(uint64(0x4ef997456198dd78), s.scramble(0))
+	require.Equal(uint64(0x64ed065757511fa7), s.scramble(1))
+	require.Equal(uint64(0xad63166cadf9811e), s.scramble(2))
+	require.Equal(uint64(0x731ca6b4131b7ef1), s.scramble(3))
+	require.Equal(uint64(0xac25f3282f17f3b2), s.scramble(4))
+}
+
+func TestGoMashMatchesC(t *testing.T) {
+	for _, asm := range []bool{false, true} {
+		if asm {
+			maybeEnableAssembly()
+		} else {
+			disableAssembly()
+		}
+		testGoMashMatchesC(t)
+	}
+	maybeEnableAssembly()
+}
+
+func testGoMashMatchesC(t *testing.T) {
+	require := require.New(t)
+	numbers := []uint64{0, 1, 2, 63, 64, 1 << 32, 1<<32 - 1, 1<<64 - 1}
+	for i := uint64(0); i < 1000; i++ {
+		numbers = append(numbers, i*0x9e3779b97f4a7c15)
+	}
+	for _, n := range numbers {
+		var c guacamole
+		c.seed(n)
+		expected := make([]byte, BlockSize)
+		c.generate(expected)
+		var actual [BlockSize]byte
+		goMash(n, &actual)
+		require.Equal(expected, actual[:], "number=%d", n)
+	}
+}
+
+func TestGoKeyedMatchesC(t *testing.T) {
+	require := require.New(t)
+	for _, b := range []byte{0, 1, 0xff} {
+		var key [32]byte
+		for i := range key {
+			key[i] = b * byte(i+1)
+		}
+		key[31] = 1
+		for _, n := range []uint64{0, 1, 1 << 32, 1<<64 - 1} {
+			var c guacamole
+			c.setKey(&key)
+			c.seek(n, 5)
+			expected := make([]byte, 4096)
+			c.generate(expected)
+			g := &goGuacamole{}
+			g.setKey(&key)
+			g.seek(n, 5)
+			actual := make([]byte, 4096)
+			g.generate(actual)
+			require.Equal(expected, actual, "key=%x number=%d", key, n)
+		}
+	}
+}
+
+// TestAssemblyMatchesC checks every kernel the processor supports against
+// guacamole_mash_c over many seeds.  On arm64 this covers the NEON kernel; the
+// arm64 job in .github/workflows/test.yml runs it natively, and it may be run
+// from an amd64 Linux host under qemu-user with something like:
+//
+//	CC=aarch64-linux-gnu-gcc CGO_ENABLED=1 GOARCH=arm64 go test \
+//	    -exec 'qemu-aarch64 -L /usr/aarch64-linux-gnu' ./guacamole
+func TestAssemblyMatchesC(t *testing.T) {
+	require := require.New(t)
+	defer maybeEnableAssembly()
+	const seeds = 1 << 16
+	seed := func(i int) uint64 {
+		return uint64(i) * 0x9e3779b97f4a7c15
+	}
+
+	require.Equal(isaC, limitAssembly(isaC))
+	expected := make([]byte, seeds*BlockSize)
+	for i := 0; i < seeds; i++ {
+		var g guacamole
+		g.seed(seed(i))
+		g.generate(expected[i*BlockSize : (i+1)*BlockSize])
+	}
+
+	isas := []int{isaSSE41, isaAVX2, isaAVX512}
+	if runtime.GOARCH == "arm64" {
+		isas = []int{isaNEON}
+	}
+	for _, isa := range isas {
+		if limitAssembly(isa) != isa {
+			// Every arm64 processor has NEON, so it is never skipped there.
+			require.NotEqual("arm64", runtime.GOARCH, "isa=%d not selected", isa)
+			t.Logf("skipping isa=%d; not supported by this processor", isa)
+			continue
+		}
+		actual := make([]byte, seeds*BlockSize)
+		for i := 0; i < seeds; i++ {
+			var g guacamole
+			g.seed(seed(i))
+			g.generate(actual[i*BlockSize : (i+1)*BlockSize])
+		}
+		for i := 0; i < seeds; i++ {
+			require.Equal(expected[i*BlockSize:(i+1)*BlockSize], actual[i*BlockSize:(i+1)*BlockSize],
+				"isa=%d seed=%d", isa, seed(i))
+		}
+		t.Logf("isa=%d matches guacamole_mash_c over %d seeds", isa, seeds)
+	}
+}
+
+func TestWideKernelsMatchGo(t *testing.T) {
+	defer maybeEnableAssembly()
+	for _, isa := range []int{isaC, isaSSE41, isaAVX2, isaAVX512} {
+		if limitAssembly(isa) != isa {
+			t.Logf("skipping isa=%d; not supported by this processor", isa)
+			continue
+		}
+		testWideKernelsMatchGo(t, isa)
+	}
+}
+
+func testWideKernelsMatchGo(t *testing.T, isa int) {
+	require := require.New(t)
+	// cover the carry from the low word of the nonce into the high word
+	seeds := []uint64{0, 1, 1<<32 - 5, 1<<64 - 3}
+	sizes := []int{0, 1, 63, 64, 65, 255, 256, 257, 511, 512, 1000, 4096, 65536 + 17}
+	for _, s := range seeds {
+		for _, offset := range []int{0, 1, 33, 63} {
+			var c guacamole
+			var g goGuacamole
+			c.seed(s)
+			g.seed(s)
+			skip := make([]byte, offset)
+			c.generate(skip)
+			g.generate(skip)
+			for _, sz := range sizes {
+				expected := make([]byte, sz)
+				actual := make([]byte, sz)
+				g.generate(expected)
+				c.generate(actual)
+				require.Equal(expected, actual, "isa=%d seed=%d offset=%d size=%d", isa, s, offset, sz)
+			}
+			require.Equal(g.double(), c.double())
+		}
+	}
+}
+
+func TestGoStreamMatchesC(t *testing.T) {
+	require := require.New(t)
+	var c guacamole
+	var g goGuacamole
+	var r goGuacamole
+	r.seed(1337)
+	for i := 0; i < 100; i++ {
+		s := uint64(i) * 0xdeadbeefcafe
+		c.seed(s)
+		g.seed(s)
+		for j := 0; j < 16; j++ {
+			var sz [2]byte
+			r.generate(sz[:])
+			n := int(binary.LittleEndian.Uint16(sz[:]) % 513)
+			expected := make([]byte, n)
+			actual := make([]byte, n)
+			c.generate(expected)
+			g.generate(actual)
+			require.Equal(expected, actual)
+			require.Equal(c.double(), g.double())
+		}
+	}
+}
+
+// The Zipf implementations agree exactly on everything but pow.  The C code
+// inherits its pow from libm, which rounds differently from math.Pow in the
+// last place for some inputs.  This is only visible in zetan and eta (as
+// rounding error) and in the rare draw for enormous N, where n*pow(...) lands
+// within an ulp of an integer.
+func TestGoZipfMatchesC(t *testing.T) {
+	require := require.New(t)
+	type parameter struct {
+		n          uint64
+		theta      float64
+		alpha      float64
+		mismatches int
+	}
+	parameters := []parameter{
+		{n: 1000, theta: 0.1},
+		{n: 1000, theta: 0.5},
+		{n: 1000, theta: 0.99},
+		{n: 1000, theta: 1.0},
+		{n: 12345, theta: 0.7},
+		{n: 1e7, theta: 0.8},
+		{n: 1e12, theta: 0.9, mismatches: 10},
+		{n: 123456789, theta: 0.83},
+		{n: 1e16, theta: 0.5, mismatches: 100},
+		{n: 1000, alpha: 2},
+		{n: 1e9, alpha: 100},
+	}
+	for _, p := range parameters {
+		var c zipfParams
+		var g goZipfParams
+		if p.alpha != 0 {
+			c.initAlpha(p.n, p.alpha)
+			g.initAlpha(p.n, p.alpha)
+		} else {
+			c.initTheta(p.n, p.theta)
+			g.initTheta(p.n, p.theta)
+		}
+		cn, calpha, ctheta, czetan, czeta2, ceta := c.dump()
+		gn, galpha, gtheta, gzetan, gzeta2, geta := g.dump()
+		require.Equal(cn, gn)
+		require.Equal(calpha, galpha)
+		require.Equal(ctheta, gtheta)
+		require.InEpsilon(czetan, gzetan, 1e-12)
+		require.Equal(czeta2, gzeta2)
+		require.InDelta(ceta, geta, 1e-12)
+
+		var cg guacamole
+		var gg goGuacamole
+		cg.seed(0)
+		gg.seed(0)
+		mismatches := 0
+		for i := 0; i < 100000; i++ {
+			if c.draw(&cg) != g.draw(&gg) {
+				mismatches++
+			}
+		}
+		require.True(mismatches <= p.mismatches, "n=%d theta=%g alpha=%g mismatches=%d", p.n, p.theta, p.alpha, mismatches)
+	}
+}
+
+func TestGoScramblerMatchesC(t *testing.T) {
+	require := require.New(t)
+	var c scrambler
+	var g goScrambler
+	for _, bijection := range []uint64{0, 1, 2, 1337, 1<<64 - 1} {
+		c.change(bijection)
+		g.change(bijection)
+		for x := uint64(0); x < 10000; x++ {
+			v := x * 0x9e3779b97f4a7c15
+			require.Equal(c.scramble(v), g.scramble(v))
+			require.Equal(c.unscramble(v), g.unscramble(v))
+		}
+	}
+}
+
+func benchmarkISA(isa, num int, b *testing.B) {
+	defer maybeEnableAssembly()
+	if limitAssembly(isa) != isa {
+		b.Skip("not supported by this processor")
+	}
+	var g guacamole
+	g.seed(0)
+	bytes := make([]byte, num)
+	b.SetBytes(int64(num))
+	for n := 0; n < b.N; n++ {
+		g.generate(bytes)
+	}
+}
+
+func BenchmarkSSE41Guacamole64KB(b *testing.B)  { benchmarkISA(isaSSE41, 65536, b) }
+func BenchmarkSSE41Guacamole1MB(b *testing.B)   { benchmarkISA(isaSSE41, 1048576, b) }
+func BenchmarkAVX2Guacamole64KB(b *testing.B)   { benchmarkISA(isaAVX2, 65536, b) }
+func BenchmarkAVX2Guacamole1MB(b *testing.B)    { benchmarkISA(isaAVX2, 1048576, b) }
+func BenchmarkAVX512Guacamole64KB(b *testing.B) { benchmarkISA(isaAVX512, 65536, b) }
+func BenchmarkAVX512Guacamole1MB(b *testing.B)  { benchmarkISA(isaAVX512, 1048576, b) }
@@ -0,0 +1,27 @@
+//go:build !cgo || purego
+// +build !cgo purego
+
+package guacamole
+
+// This file binds the exported API to the native Go implementation.  It is
+// used when cgo is unavailable (e.g. CGO_ENABLED=0 or cross-compiling) or when
+// the purego build tag is provided.  The output is byte-for-byte identical to
+// the C implementation except for Zipf, which relies upon pow.  The C
+// implementation inherits pow from libm, and math.Pow occasionally rounds the
+// last place differently, which is visible in draws from very large (N > 1e10)
+// distributions at a rate of roughly one draw in 50,000.  See the package
+// documentation.
+
+func disableAssembly() {
+	goDisableAssembly()
+}
+
+func maybeEnableAssembly() {
+	goMaybeEnableAssembly()
+}
+
+type guacamole = goGuacamole
+
+type zipfParams = goZipfParams
+
+type scrambler = goScrambler
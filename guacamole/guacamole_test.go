@@ -2,7 +2,10 @@ package guacamole_test
 
 import (
 	"math"
+	"math/rand"
 	"testing"
+	"testing/quick"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -69,6 +72,28 @@ func testGuacamole(t *testing.T) {
 	}
 }
 
+func TestFillParallel(t *testing.T) {
+	require := require.New(t)
+	sizes := []int{0, 1, 63, 64, 65, 1 << 16, 1<<20 + 17, 4<<20 + 64}
+	for _, offset := range []uint64{0, 1, 63, 64, 100} {
+		for _, sz := range sizes {
+			for _, workers := range []int{0, 1, 2, 3, 8} {
+				seq := guacamole.New()
+				par := guacamole.New()
+				seq.Seek(1<<32-1, offset)
+				par.Seek(1<<32-1, offset)
+				expected := make([]byte, sz)
+				actual := make([]byte, sz)
+				seq.Fill(expected)
+				par.FillParallel(actual, workers)
+				require.Equal(expected, actual, "offset=%d size=%d workers=%d", offset, sz, workers)
+				// the generators must be left in the same state
+				require.Equal(seq.Bytes(100), par.Bytes(100))
+			}
+		}
+	}
+}
+
 func TestGuacamolePassToFunction(t *testing.T) {
 	require := require.New(t)
 	g := guacamole.New()
@@ -118,6 +143,23 @@ func TestZipf(t *testing.T) {
 	}
 }
 
+// TestZipfUncommonParameters checks that sets outside the precomputed table
+// initialize quickly.
+func TestZipfUncommonParameters(t *testing.T) {
+	require := require.New(t)
+	start := time.Now()
+	zp := guacamole.ZipfTheta(123456789, 0.83)
+	require.True(time.Since(start) < time.Second)
+	N, _, _, zetan, _, _ := zp.Dump()
+	require.Equal(uint64(123456789), N)
+	require.True(zetan > 1)
+	g := guacamole.New()
+	for i := 0; i < 1000; i++ {
+		x := g.Zipf(zp)
+		require.True(x >= 1 && x <= N)
+	}
+}
+
 func TestScrambler(t *testing.T) {
 	require := require.New(t)
 
@@ -130,6 +172,34 @@ func TestScrambler(t *testing.T) {
 	require.Equal(uint64(0xac25f3282f17f3b2), s.Scramble(4))
 }
 
+func TestUnscramble(t *testing.T) {
+	require := require.New(t)
+
+	s := guacamole.NewScrambler()
+	require.Equal(uint64(0), s.Unscramble(0x4ef997456198dd78))
+	require.Equal(uint64(1), s.Unscramble(0x64ed065757511fa7))
+	require.Equal(uint64(2), s.Unscramble(0xad63166cadf9811e))
+	require.Equal(uint64(3), s.Unscramble(0x731ca6b4131b7ef1))
+	require.Equal(uint64(4), s.Unscramble(0xac25f3282f17f3b2))
+
+	// Unscramble inverts Scramble, and Scramble inverts Unscramble, for
+	// every bijection
+	roundTrip := func(bijection, x uint64) bool {
+		s.Change(bijection)
+		return s.Unscramble(s.Scramble(x)) == x && s.Scramble(s.Unscramble(x)) == x
+	}
+	config := &quick.Config{
+		MaxCount: 1000,
+		Rand:     rand.New(guacamole.NewSource(0)),
+	}
+	require.NoError(quick.Check(roundTrip, config))
+	for _, bijection := range []uint64{0, 1, 1<<64 - 1} {
+		for _, x := range []uint64{0, 1, 1<<32 - 1, 1 << 32, 1<<64 - 1} {
+			require.True(roundTrip(bijection, x))
+		}
+	}
+}
+
 func benchmarkGuacamoleBytes(num int, maybeASM bool, b *testing.B) []byte {
 	if maybeASM {
 		guacamole.MaybeEnableAssembly()
@@ -156,6 +226,50 @@ func BenchmarkASMGuacamole4KB(b *testing.B)  { benchmarkGuacamoleBytes(4096, tru
 func BenchmarkASMGuacamole64KB(b *testing.B) { benchmarkGuacamoleBytes(65536, true, b) }
 func BenchmarkASMGuacamole1MB(b *testing.B)  { benchmarkGuacamoleBytes(1048576, true, b) }
 
+func benchmarkGoGuacamoleBytes(num int, maybeASM bool, b *testing.B) []byte {
+	if maybeASM {
+		if !guacamole.GoAssemblyAvailable() {
+			b.Skip("the Go assembly is not built or not supported by this processor")
+		}
+		guacamole.GoMaybeEnableAssembly()
+	} else {
+		guacamole.GoDisableAssembly()
+	}
+	defer guacamole.GoMaybeEnableAssembly()
+	fill := guacamole.NewGoFill()
+	bytes := make([]byte, num)
+	for n := 0; n < b.N; n++ {
+		fill(bytes)
+	}
+	return bytes
+}
+
+func BenchmarkGoGuacamole64B(b *testing.B)  { benchmarkGoGuacamoleBytes(64, false, b) }
+func BenchmarkGoGuacamole1KB(b *testing.B)  { benchmarkGoGuacamoleBytes(1024, false, b) }
+func BenchmarkGoGuacamole4KB(b *testing.B)  { benchmarkGoGuacamoleBytes(4096, false, b) }
+func BenchmarkGoGuacamole64KB(b *testing.B) { benchmarkGoGuacamoleBytes(65536, false, b) }
+func BenchmarkGoGuacamole1MB(b *testing.B)  { benchmarkGoGuacamoleBytes(1048576, false, b) }
+
+func BenchmarkGoASMGuacamole64B(b *testing.B)  { benchmarkGoGuacamoleBytes(64, true, b) }
+func BenchmarkGoASMGuacamole1KB(b *testing.B)  { benchmarkGoGuacamoleBytes(1024, true, b) }
+func BenchmarkGoASMGuacamole4KB(b *testing.B)  { benchmarkGoGuacamoleBytes(4096, true, b) }
+func BenchmarkGoASMGuacamole64KB(b *testing.B) { benchmarkGoGuacamoleBytes(65536, true, b) }
+func BenchmarkGoASMGuacamole1MB(b *testing.B)  { benchmarkGoGuacamoleBytes(1048576, true, b) }
+
+func benchmarkGuacamoleParallel(num int, b *testing.B) []byte {
+	guacamole.MaybeEnableAssembly()
+	g := guacamole.New()
+	bytes := make([]byte, num)
+	b.SetBytes(int64(num))
+	for n := 0; n < b.N; n++ {
+		g.FillParallel(bytes, 0)
+	}
+	return bytes
+}
+
+func BenchmarkParallelGuacamole1MB(b *testing.B)  { benchmarkGuacamoleParallel(1048576, b) }
+func BenchmarkParallelGuacamole64MB(b *testing.B) { benchmarkGuacamoleParallel(67108864, b) }
+
 var result uint64
 
 func benchmarkZipfTheta(N uint64, theta float64, b *testing.B) {
@@ -198,6 +312,15 @@ func BenchmarkScramblerScramble(b *testing.B) {
 	result = sum
 }
 
+func BenchmarkScramblerUnscramble(b *testing.B) {
+	s := guacamole.NewScrambler()
+	sum := uint64(0)
+	for n := 0; n < b.N; n++ {
+		sum += s.Unscramble(uint64(n))
+	}
+	result = sum
+}
+
 func BenchmarkScramblerStrawMan(b *testing.B) {
 	sum := uint64(0)
 	for n := 0; n < b.N; n++ {
@@ -0,0 +1,65 @@
+// Package guacamoletest provides guacamole generators for tests whose seeds
+// are derived from the test name and may be overridden to replay a failure.
+//
+// Instead of hard-coding a seed,
+//
+//	g := guacamoletest.New(t)
+//
+// seeds g with guacamole.SeedFromName(t.Name()).  Every test thus draws from
+// its own region of the stream, the seed stays the same from run to run, and
+// renaming a test is the only thing that changes it.  If the test fails, the
+// seed is logged along with how to replay it.  The seed may be overridden with
+// the -guacamole.seed flag or the GUACAMOLE_SEED environment variable, the
+// flag taking precedence, e.g. to replay a failure logged by another test or
+// to try a test against other seeds:
+//
+//	GUACAMOLE_SEED=0x1f06ab51895ee53b go test -run TestFoo ./...
+package guacamoletest
+
+import (
+	"flag"
+	"os"
+	"strconv"
+	"testing"
+
+	"hack.systems/random/guacamole"
+)
+
+// SeedEnv is the environment variable that overrides the seed.
+const SeedEnv = "GUACAMOLE_SEED"
+
+var seedFlag = flag.String("guacamole.seed", "", "override the seed of guacamoletest generators (or set "+SeedEnv+")")
+
+// Seed returns the seed for t:  guacamole.SeedFromName(t.Name()), unless the
+// flag or environment variable overrides it.  An override is a uint64 in
+// decimal, or in hexadecimal with a 0x prefix.  When t fails, Seed logs the
+// seed and how to replay it.  Seed fails t if the override cannot be parsed.
+func Seed(t testing.TB) uint64 {
+	t.Helper()
+	seed := guacamole.SeedFromName(t.Name())
+	override, source := *seedFlag, "-guacamole.seed"
+	if override == "" {
+		override, source = os.Getenv(SeedEnv), SeedEnv
+	}
+	if override != "" {
+		s, err := strconv.ParseUint(override, 0, 64)
+		if err != nil {
+			t.Fatalf("guacamoletest: invalid seed %q in %s: %v", override, source, err)
+		}
+		seed = s
+	}
+	t.Cleanup(func() {
+		if t.Failed() {
+			t.Logf("guacamole seed %#x; replay with %s=%#x or -guacamole.seed=%#x", seed, SeedEnv, seed, seed)
+		}
+	})
+	return seed
+}
+
+// New returns a generator seeded with Seed(t).
+func New(t testing.TB) *guacamole.Guacamole {
+	t.Helper()
+	g := guacamole.New()
+	g.Seed(Seed(t))
+	return g
+}
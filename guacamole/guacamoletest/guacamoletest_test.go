@@ -0,0 +1,90 @@
+package guacamoletest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"hack.systems/random/guacamole"
+)
+
+// recorder stands in for a *testing.T to observe what Seed does with it.
+type recorder struct {
+	testing.TB
+	name     string
+	failed   bool
+	fatal    string
+	logs     []string
+	cleanups []func()
+}
+
+func (r *recorder) Name() string     { return r.name }
+func (r *recorder) Helper()          {}
+func (r *recorder) Failed() bool     { return r.failed }
+func (r *recorder) Cleanup(f func()) { r.cleanups = append(r.cleanups, f) }
+
+func (r *recorder) Logf(format string, args ...interface{}) {
+	r.logs = append(r.logs, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.fatal = fmt.Sprintf(format, args...)
+}
+
+func (r *recorder) finish(failed bool) {
+	r.failed = failed
+	for _, f := range r.cleanups {
+		f()
+	}
+}
+
+func TestSeedFromName(t *testing.T) {
+	require := require.New(t)
+	t.Setenv(SeedEnv, "")
+	require.Equal(guacamole.SeedFromName("TestSeedFromName"), Seed(t))
+
+	expected := guacamole.New()
+	expected.Seed(guacamole.SeedFromName("TestSeedFromName"))
+	require.Equal(expected.Bytes(64), New(t).Bytes(64))
+}
+
+func TestSeedLoggedOnFailure(t *testing.T) {
+	require := require.New(t)
+	t.Setenv(SeedEnv, "")
+
+	r := &recorder{name: "TestGuacamole"}
+	require.Equal(uint64(0x1f06ab51895ee53b), Seed(r))
+	r.finish(false)
+	require.Empty(r.logs)
+
+	r = &recorder{name: "TestGuacamole"}
+	Seed(r)
+	r.finish(true)
+	require.Equal([]string{"guacamole seed 0x1f06ab51895ee53b; replay with " +
+		"GUACAMOLE_SEED=0x1f06ab51895ee53b or -guacamole.seed=0x1f06ab51895ee53b"}, r.logs)
+}
+
+func TestSeedOverride(t *testing.T) {
+	require := require.New(t)
+
+	t.Setenv(SeedEnv, "1337")
+	require.Equal(uint64(1337), Seed(t))
+	t.Setenv(SeedEnv, "0x1f06ab51895ee53b")
+	require.Equal(uint64(0x1f06ab51895ee53b), Seed(t))
+
+	// the flag takes precedence over the environment
+	*seedFlag = "42"
+	defer func() { *seedFlag = "" }()
+	require.Equal(uint64(42), Seed(t))
+
+	r := &recorder{name: "TestGuacamole"}
+	Seed(r)
+	r.finish(true)
+	require.Contains(r.logs[0], "guacamole seed 0x2a;")
+
+	*seedFlag = "banana"
+	r = &recorder{name: "TestGuacamole"}
+	Seed(r)
+	require.Contains(r.fatal, `invalid seed "banana" in -guacamole.seed`)
+}
@@ -0,0 +1,99 @@
+package guacamole_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"hack.systems/random/guacamole"
+)
+
+func testKey(b byte) [32]byte {
+	var key [32]byte
+	for i := range key {
+		key[i] = b + byte(i)
+	}
+	return key
+}
+
+func TestKeyedZeroIsUnkeyed(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.NewKeyed([32]byte{})
+	require.Equal([]byte(First8Bytes), g.Bytes(8))
+
+	expected := guacamole.New()
+	expected.Seed(1337)
+	g.Seed(1337)
+	require.Equal(expected.Bytes(100000), g.Bytes(100000))
+}
+
+func TestKeyedKnownAnswers(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.NewKeyed(testKey(1))
+	require.Equal(uint64(0xfd2360b7fcfd047f), g.Uint64())
+	g.Seed(1<<64 - 1)
+	require.Equal(uint64(0xf6acef91ed92547e), g.Uint64())
+	g.SetKey([32]byte{31: 1})
+	g.Seed(0)
+	require.Equal(uint64(0xaf1e4de09eabe4ec), g.Uint64())
+}
+
+func TestKeyedStreams(t *testing.T) {
+	require := require.New(t)
+	a := guacamole.NewKeyed(testKey(1))
+	b := guacamole.NewKeyed(testKey(2))
+	a.Seed(1000)
+	b.Seed(1000)
+	streamA := a.Bytes(1 << 16)
+	streamB := b.Bytes(1 << 16)
+	require.NotEqual(streamA, streamB)
+	// no block of one stream appears in the other, or in the unkeyed stream
+	unkeyed := guacamole.New()
+	unkeyed.Seed(1000)
+	streamU := unkeyed.Bytes(1 << 16)
+	for i := 0; i < len(streamA); i += guacamole.BlockSize {
+		block := streamA[i : i+guacamole.BlockSize]
+		require.False(bytes.Contains(streamB, block))
+		require.False(bytes.Contains(streamU, block))
+	}
+
+	// each keyed stream keeps the linear seeding property
+	a.Seed(1001)
+	require.Equal(streamA[guacamole.BlockSize:2*guacamole.BlockSize], a.Bytes(guacamole.BlockSize))
+	a.Seek(1000, 1000)
+	require.Equal(streamA[1000:2000], a.Bytes(1000))
+	seed, offset := a.Position()
+	require.Equal(uint64(1000), seed)
+	require.Equal(uint64(2000), offset)
+
+	// SetKey keeps the position
+	a.Seek(1000, 100)
+	a.SetKey(testKey(2))
+	require.Equal(streamB[100:200], a.Bytes(100))
+	a.SetKey([32]byte{})
+	require.Equal(streamU[200:300], a.Bytes(100))
+}
+
+func TestKeyedFillParallel(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.NewKeyed(testKey(7))
+	g.Seek(5, 3)
+	expected := g.Bytes(1 << 20)
+	g.Seek(5, 3)
+	actual := make([]byte, 1<<20)
+	g.FillParallel(actual, 4)
+	require.Equal(expected, actual)
+	next := g.Bytes(100)
+	g.Seek(5, 3+1<<20)
+	require.Equal(g.Bytes(100), next)
+}
+
+func BenchmarkKeyedGuacamole64KB(b *testing.B) {
+	g := guacamole.NewKeyed(testKey(1))
+	bytes := make([]byte, 65536)
+	b.SetBytes(int64(len(bytes)))
+	for n := 0; n < b.N; n++ {
+		g.Fill(bytes)
+	}
+}
@@ -0,0 +1,206 @@
+package guacamole
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	errMarshaledPosition = errors.New("guacamole: invalid marshaled position")
+	errMarshaledVersion  = errors.New("guacamole: marshaled position has an unsupported algorithm version")
+)
+
+// marshalFormat is the first byte of the binary encoding.
+const marshalFormat = 1
+
+// Position returns the logical position of the generator as the seed most
+// recently passed to Seed or Seek and the number of bytes generated since.
+// Seek(seed, offset) returns the generator to exactly this position.
+//
+// If more than 2^58 blocks have been generated since the seed was set, the
+// offset would overflow; Position then reports the current block as the seed.
+func (g *Guacamole) Position() (seed, offset uint64) {
+	nonce, index := g.guac.tell()
+	blocks := nonce - g.seed
+	if blocks >= 1<<58 {
+		return nonce, uint64(index)
+	}
+	return g.seed, blocks*BlockSize + uint64(index)
+}
+
+// restore sets the version, key, position and split count of a generator
+// being unmarshaled.
+func (g *Guacamole) restore(v Version, key *[32]byte, seed, offset, splits uint64) error {
+	if !v.Supported() {
+		return errMarshaledVersion
+	}
+	g.version = v
+	g.SetKey(*key)
+	g.Seek(seed, offset)
+	g.splits = splits
+	return nil
+}
+
+// keyed reports whether the generator has a nonzero key.
+func (g *Guacamole) keyed() bool {
+	return g.key != [32]byte{}
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.  The encoding records the
+// generator's Version, its Position, the number of children it has returned
+// from Split, and its key compactly, so a generator may be checkpointed and
+// later resumed exactly where it stopped, splitting off new children rather
+// than repeating earlier ones.  The key is only written when it is nonzero,
+// and is written in the clear; a checkpoint of a keyed generator reveals the
+// key.
+func (g *Guacamole) MarshalBinary() ([]byte, error) {
+	seed, offset := g.Position()
+	buf := make([]byte, 1, 1+4*binary.MaxVarintLen64+32)
+	buf[0] = marshalFormat
+	buf = binary.AppendUvarint(buf, uint64(g.Version()))
+	buf = binary.AppendUvarint(buf, seed)
+	buf = binary.AppendUvarint(buf, offset)
+	buf = binary.AppendUvarint(buf, g.splits)
+	if g.keyed() {
+		buf = append(buf, g.key[:]...)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler by restoring the
+// state recorded by MarshalBinary.
+func (g *Guacamole) UnmarshalBinary(data []byte) error {
+	if len(data) < 1 || data[0] != marshalFormat {
+		return errMarshaledPosition
+	}
+	data = data[1:]
+	var fields [4]uint64
+	for i := range fields {
+		x, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errMarshaledPosition
+		}
+		fields[i] = x
+		data = data[n:]
+	}
+	var key [32]byte
+	switch len(data) {
+	case 0:
+	case len(key):
+		copy(key[:], data)
+		if key == [32]byte{} {
+			return errMarshaledPosition
+		}
+	default:
+		return errMarshaledPosition
+	}
+	if fields[0] > uint64(LatestVersion) {
+		return errMarshaledVersion
+	}
+	return g.restore(Version(fields[0]), &key, fields[1], fields[2], fields[3])
+}
+
+// MarshalText implements encoding.TextMarshaler.  The version, position and
+// split count are written as "vVERSION:seed:offset:splits", e.g.
+// "v1:1337:4096:0", followed for a keyed generator by a colon and the key in
+// lowercase hexadecimal.  As with MarshalBinary, the key is written in the
+// clear.
+func (g *Guacamole) MarshalText() ([]byte, error) {
+	seed, offset := g.Position()
+	text := fmt.Sprintf("v%d:%d:%d:%d", g.Version(), seed, offset, g.splits)
+	if g.keyed() {
+		text += ":" + hex.EncodeToString(g.key[:])
+	}
+	return []byte(text), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler by restoring the state
+// recorded by MarshalText.
+func (g *Guacamole) UnmarshalText(text []byte) error {
+	rest, ok := strings.CutPrefix(string(text), "v")
+	if !ok {
+		return errMarshaledPosition
+	}
+	fields := strings.Split(rest, ":")
+	if len(fields) != 4 && len(fields) != 5 {
+		return errMarshaledPosition
+	}
+	var numbers [4]uint64
+	for i := range numbers {
+		x, err := strconv.ParseUint(fields[i], 10, 64)
+		if err != nil {
+			return errMarshaledPosition
+		}
+		numbers[i] = x
+	}
+	var key [32]byte
+	if len(fields) == 5 {
+		var err error
+		if key, err = parseKey(fields[4]); err != nil {
+			return err
+		}
+	}
+	if numbers[0] > uint64(LatestVersion) {
+		return errMarshaledVersion
+	}
+	return g.restore(Version(numbers[0]), &key, numbers[1], numbers[2], numbers[3])
+}
+
+// parseKey parses the nonzero key written by MarshalText and MarshalJSON.
+func parseKey(text string) ([32]byte, error) {
+	var key [32]byte
+	if len(text) != hex.EncodedLen(len(key)) || strings.ToLower(text) != text {
+		return key, errMarshaledPosition
+	}
+	if _, err := hex.Decode(key[:], []byte(text)); err != nil {
+		return key, errMarshaledPosition
+	}
+	if key == [32]byte{} {
+		return key, errMarshaledPosition
+	}
+	return key, nil
+}
+
+type jsonPosition struct {
+	Version Version `json:"version"`
+	Seed    uint64  `json:"seed"`
+	Offset  uint64  `json:"offset"`
+	Splits  uint64  `json:"splits,omitempty"`
+	Key     string  `json:"key,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.  The version and position are written
+// as an object like {"version":1,"seed":1337,"offset":4096}, with a "splits"
+// member for a generator that has returned children from Split and a "key"
+// member holding the key in lowercase hexadecimal for a keyed generator.  As
+// with MarshalBinary, the key is written in the clear.
+func (g *Guacamole) MarshalJSON() ([]byte, error) {
+	seed, offset := g.Position()
+	pos := jsonPosition{Version: g.Version(), Seed: seed, Offset: offset, Splits: g.splits}
+	if g.keyed() {
+		pos.Key = hex.EncodeToString(g.key[:])
+	}
+	return json.Marshal(pos)
+}
+
+// UnmarshalJSON implements json.Unmarshaler by restoring the state recorded by
+// MarshalJSON.  The version is required.
+func (g *Guacamole) UnmarshalJSON(data []byte) error {
+	var pos jsonPosition
+	if err := json.Unmarshal(data, &pos); err != nil {
+		return err
+	}
+	var key [32]byte
+	if pos.Key != "" {
+		var err error
+		if key, err = parseKey(pos.Key); err != nil {
+			return err
+		}
+	}
+	return g.restore(pos.Version, &key, pos.Seed, pos.Offset, pos.Splits)
+}
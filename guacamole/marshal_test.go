@@ -0,0 +1,172 @@
+package guacamole_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"hack.systems/random/guacamole"
+)
+
+func TestPosition(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+	g.Seed(1337)
+	seed, offset := g.Position()
+	require.Equal(uint64(1337), seed)
+	require.Equal(uint64(0), offset)
+
+	_ = g.Bytes(1000)
+	seed, offset = g.Position()
+	require.Equal(uint64(1337), seed)
+	require.Equal(uint64(1000), offset)
+
+	g.Seek(42, 4097)
+	seed, offset = g.Position()
+	require.Equal(uint64(42), seed)
+	require.Equal(uint64(4097), offset)
+
+	g.FillParallel(make([]byte, 1<<20), 4)
+	seed, offset = g.Position()
+	require.Equal(uint64(42), seed)
+	require.Equal(uint64(4097+1<<20), offset)
+}
+
+func TestMarshalBinary(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+	g.Seed(1337)
+	_ = g.Bytes(12345)
+
+	data, err := g.MarshalBinary()
+	require.NoError(err)
+	require.True(len(data) <= 1+4*10)
+	require.Equal([]byte{1, 1}, data[:2])
+	expected := g.Bytes(100)
+
+	r := &guacamole.Guacamole{}
+	require.NoError(r.UnmarshalBinary(data))
+	require.Equal(guacamole.Version1, r.Version())
+	require.Equal(expected, r.Bytes(100))
+
+	require.Error(r.UnmarshalBinary(nil))
+	require.Error(r.UnmarshalBinary([]byte{0xff, 1, 1}))
+	require.Error(r.UnmarshalBinary(data[:len(data)-1]))
+	require.Error(r.UnmarshalBinary(append(data, 0)))
+	require.Error(r.UnmarshalBinary(append([]byte{2}, data[1:]...)))
+	require.Error(r.UnmarshalBinary(append(data, make([]byte, 32)...)))
+	for _, v := range []byte{0, byte(guacamole.LatestVersion + 1)} {
+		unsupported := append([]byte{1, v}, data[2:]...)
+		require.Error(r.UnmarshalBinary(unsupported), "version %d", v)
+	}
+}
+
+func TestMarshalText(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+	g.Seek(1<<64-1, 100)
+
+	text, err := g.MarshalText()
+	require.NoError(err)
+	require.Equal("v1:18446744073709551615:100:0", string(text))
+	expected := g.Bytes(100)
+
+	r := &guacamole.Guacamole{}
+	require.NoError(r.UnmarshalText(text))
+	require.Equal(guacamole.Version1, r.Version())
+	require.Equal(expected, r.Bytes(100))
+
+	require.Error(r.UnmarshalText([]byte("v0:1337:12:0")))
+	require.Error(r.UnmarshalText([]byte(fmt.Sprintf("v%d:1337:12:0", guacamole.LatestVersion+1))))
+	require.Error(r.UnmarshalText([]byte("v:1337:12:0")))
+	require.Error(r.UnmarshalText([]byte("v1:1337:12")))
+	require.Error(r.UnmarshalText([]byte("v1 :1337:12:0")))
+
+	require.Error(r.UnmarshalText([]byte("1337:12:0")))
+	require.Error(r.UnmarshalText([]byte("v1:1337::0")))
+	require.Error(r.UnmarshalText([]byte("v1:1337:12x:0")))
+	require.Error(r.UnmarshalText([]byte("v1:1337:12:0 ")))
+	require.Error(r.UnmarshalText([]byte("v1:1337:12:0\n")))
+	require.Error(r.UnmarshalText([]byte("v1: 1337:12:0")))
+	require.Error(r.UnmarshalText([]byte("v1:+1337:12:0")))
+	require.Error(r.UnmarshalText([]byte("v1:1337:-12:0")))
+	require.Error(r.UnmarshalText([]byte("v1:1337:12:0:1")))
+	require.Error(r.UnmarshalText([]byte("v1:1337:12:0:")))
+	require.Error(r.UnmarshalText([]byte("v1:18446744073709551616:0:0")))
+}
+
+func TestMarshalJSON(t *testing.T) {
+	require := require.New(t)
+	type checkpoint struct {
+		Name string               `json:"name"`
+		Guac *guacamole.Guacamole `json:"guac"`
+	}
+	g := guacamole.New()
+	g.Seed(7)
+	_ = g.Bytes(65)
+
+	data, err := json.Marshal(checkpoint{Name: "loader", Guac: g})
+	require.NoError(err)
+	require.JSONEq(`{"name":"loader","guac":{"version":1,"seed":7,"offset":65}}`, string(data))
+	expected := g.Bytes(100)
+
+	var c checkpoint
+	require.NoError(json.Unmarshal(data, &c))
+	require.Equal(guacamole.Version1, c.Guac.Version())
+	require.Equal(expected, c.Guac.Bytes(100))
+
+	require.Error(json.Unmarshal([]byte(`{"guac":{"seed":7,"offset":65}}`), &c))
+	unsupported := fmt.Sprintf(`{"guac":{"version":%d,"seed":7,"offset":65}}`, guacamole.LatestVersion+1)
+	require.Error(json.Unmarshal([]byte(unsupported), &c))
+	require.Error(json.Unmarshal([]byte(`{"guac":{"version":0,"seed":7,"offset":65}}`), &c))
+}
+
+func TestMarshalKeyed(t *testing.T) {
+	require := require.New(t)
+	key := testKey(1)
+	g := guacamole.NewKeyed(key)
+	g.Seed(1337)
+	_ = g.Bytes(100)
+	expected := g.Bytes(100)
+	g.Seek(1337, 100)
+	hexKey := "0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20"
+
+	data, err := g.MarshalBinary()
+	require.NoError(err)
+	require.Equal(key[:], data[len(data)-32:])
+	text, err := g.MarshalText()
+	require.NoError(err)
+	require.Equal("v1:1337:100:0:"+hexKey, string(text))
+	js, err := g.MarshalJSON()
+	require.NoError(err)
+	require.JSONEq(`{"version":1,"seed":1337,"offset":100,"key":"`+hexKey+`"}`, string(js))
+
+	// each form restores the key into an unkeyed generator
+	for _, unmarshal := range []func(*guacamole.Guacamole) error{
+		func(r *guacamole.Guacamole) error { return r.UnmarshalBinary(data) },
+		func(r *guacamole.Guacamole) error { return r.UnmarshalText(text) },
+		func(r *guacamole.Guacamole) error { return r.UnmarshalJSON(js) },
+	} {
+		r := guacamole.New()
+		require.NoError(unmarshal(r))
+		require.Equal(expected, r.Bytes(100))
+	}
+
+	// and an unkeyed position clears the key of a keyed generator
+	u := guacamole.New()
+	u.Seek(1337, 100)
+	unkeyed, err := u.MarshalText()
+	require.NoError(err)
+	r := guacamole.NewKeyed(key)
+	require.NoError(r.UnmarshalText(unkeyed))
+	require.Equal(u.Bytes(100), r.Bytes(100))
+
+	require.Error(r.UnmarshalText([]byte("v1:1337:100:0:" + hexKey[2:])))
+	require.Error(r.UnmarshalText([]byte("v1:1337:100:0:" + strings.ToUpper(hexKey))))
+	require.Error(r.UnmarshalText([]byte("v1:1337:100:0:" + strings.Repeat("0", 64))))
+	require.Error(r.UnmarshalText([]byte("v1:1337:100:0:" + hexKey[:62] + "zz")))
+	require.Error(r.UnmarshalJSON([]byte(`{"version":1,"seed":1337,"offset":100,"key":"01"}`)))
+}
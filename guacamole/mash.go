@@ -0,0 +1,310 @@
+package guacamole
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// This file contains the native Go implementation of guacamole.  It is always
+// compiled so that it may be checked against the C implementation, but it is
+// only used to back the exported API when cgo is unavailable or the purego
+// build tag is provided.  See guacamole_purego.go.
+
+// goMash is the Go equivalent of guacamole_mash.  It turns a 64-bit number
+// into 64 bytes of output, laid out exactly as the C implementation lays out
+// its uint32_t[16] on a little-endian machine.
+func goMash(number uint64, output *[BlockSize]byte) {
+	// A branch rather than a function pointer lets escape analysis see that
+	// output does not escape, so callers may mash into the stack.
+	if goMashUseAssembly {
+		goMashAssembly(number, output)
+	} else {
+		goMashGeneric(number, output)
+	}
+}
+
+var goMashUseAssembly = false
+
+// goDisableAssembly is the Go equivalent of guacamole_disable_assembly.
+func goDisableAssembly() {
+	goMashUseAssembly = false
+}
+
+// goMaybeEnableAssembly is the Go equivalent of
+// guacamole_maybe_enable_assembly.  The assembly is only available when the
+// package is built without cgo; see goCPUDetect.
+func goMaybeEnableAssembly() {
+	goMashUseAssembly = goCPUDetect()
+}
+
+// goMashGeneric is a direct translation of guacamole_mash_c.  The registers
+// x0..x15 are named the same as they are in the C code; the comments beside
+// each quad-rotate show the C macro being expanded.
+func goMashGeneric(number uint64, output *[BlockSize]byte) {
+	low := uint32(number)
+	high := uint32(number >> 32)
+
+	x0 := uint32(1634760805)
+	x1 := uint32(0)
+	x2 := uint32(0)
+	x3 := uint32(0)
+	x4 := uint32(0)
+	x5 := uint32(857760878)
+	x6 := low
+	x7 := high
+	x8 := uint32(0)
+	x9 := uint32(0)
+	x10 := uint32(2036477234)
+	x11 := uint32(0)
+	x12 := uint32(0)
+	x13 := uint32(0)
+	x14 := uint32(0)
+	x15 := uint32(1797285236)
+
+	for i := 8; i > 0; i -= 2 {
+		// QUAD_ROTATE(A, B, C, 7)
+		x4 ^= bits.RotateLeft32(x8+x12, 7)
+		x9 ^= bits.RotateLeft32(x13+x1, 7)
+		x14 ^= bits.RotateLeft32(x2+x6, 7)
+		x3 ^= bits.RotateLeft32(x7+x11, 7)
+		// QUAD_ROTATE(B, C, D, 9)
+		x8 ^= bits.RotateLeft32(x12+x0, 9)
+		x13 ^= bits.RotateLeft32(x1+x5, 9)
+		x2 ^= bits.RotateLeft32(x6+x10, 9)
+		x7 ^= bits.RotateLeft32(x11+x15, 9)
+		// QUAD_ROTATE(C, D, A, 13)
+		x12 ^= bits.RotateLeft32(x0+x4, 13)
+		x1 ^= bits.RotateLeft32(x5+x9, 13)
+		x6 ^= bits.RotateLeft32(x10+x14, 13)
+		x11 ^= bits.RotateLeft32(x15+x3, 13)
+		// QUAD_ROTATE(D, A, B, 18)
+		x0 ^= bits.RotateLeft32(x4+x8, 18)
+		x5 ^= bits.RotateLeft32(x9+x13, 18)
+		x10 ^= bits.RotateLeft32(x14+x2, 18)
+		x15 ^= bits.RotateLeft32(x3+x7, 18)
+
+		// QUAD_ROTATE(E, F, G, 7)
+		x1 ^= bits.RotateLeft32(x2+x3, 7)
+		x6 ^= bits.RotateLeft32(x7+x4, 7)
+		x11 ^= bits.RotateLeft32(x8+x9, 7)
+		x12 ^= bits.RotateLeft32(x13+x14, 7)
+		// QUAD_ROTATE(F, G, D, 9)
+		x2 ^= bits.RotateLeft32(x3+x0, 9)
+		x7 ^= bits.RotateLeft32(x4+x5, 9)
+		x8 ^= bits.RotateLeft32(x9+x10, 9)
+		x13 ^= bits.RotateLeft32(x14+x15, 9)
+		// QUAD_ROTATE(G, D, E, 13)
+		x3 ^= bits.RotateLeft32(x0+x1, 13)
+		x4 ^= bits.RotateLeft32(x5+x6, 13)
+		x9 ^= bits.RotateLeft32(x10+x11, 13)
+		x14 ^= bits.RotateLeft32(x15+x12, 13)
+		// QUAD_ROTATE(D, E, F, 18)
+		x0 ^= bits.RotateLeft32(x1+x2, 18)
+		x5 ^= bits.RotateLeft32(x6+x7, 18)
+		x10 ^= bits.RotateLeft32(x11+x8, 18)
+		x15 ^= bits.RotateLeft32(x12+x13, 18)
+	}
+
+	x0 += 1634760805
+	x5 += 857760878
+	x6 += low
+	x7 += high
+	x10 += 2036477234
+	x15 += 1797285236
+
+	// QUAD_OUTPUT(output, A)
+	binary.LittleEndian.PutUint32(output[0:], x4)
+	binary.LittleEndian.PutUint32(output[4:], x9)
+	binary.LittleEndian.PutUint32(output[8:], x14)
+	binary.LittleEndian.PutUint32(output[12:], x3)
+	// QUAD_OUTPUT(output + 4, B)
+	binary.LittleEndian.PutUint32(output[16:], x8)
+	binary.LittleEndian.PutUint32(output[20:], x13)
+	binary.LittleEndian.PutUint32(output[24:], x2)
+	binary.LittleEndian.PutUint32(output[28:], x7)
+	// QUAD_OUTPUT(output + 8, C)
+	binary.LittleEndian.PutUint32(output[32:], x12)
+	binary.LittleEndian.PutUint32(output[36:], x1)
+	binary.LittleEndian.PutUint32(output[40:], x6)
+	binary.LittleEndian.PutUint32(output[44:], x11)
+	// QUAD_OUTPUT(output + 12, D)
+	binary.LittleEndian.PutUint32(output[48:], x0)
+	binary.LittleEndian.PutUint32(output[52:], x5)
+	binary.LittleEndian.PutUint32(output[56:], x10)
+	binary.LittleEndian.PutUint32(output[60:], x15)
+}
+
+// goMashKeyed is a direct translation of guacamole_mash_keyed_c.  It is
+// goMashGeneric with the key in the words Salsa reserves for it, which the
+// unkeyed stream leaves zero.  It is kept separate because the constant zeros
+// make goMashGeneric nearly twice as fast.
+func goMashKeyed(key *[8]uint32, number uint64, output *[BlockSize]byte) {
+	low := uint32(number)
+	high := uint32(number >> 32)
+
+	x0 := uint32(1634760805)
+	x1 := key[0]
+	x2 := key[1]
+	x3 := key[2]
+	x4 := key[3]
+	x5 := uint32(857760878)
+	x6 := low
+	x7 := high
+	x8 := uint32(0)
+	x9 := uint32(0)
+	x10 := uint32(2036477234)
+	x11 := key[4]
+	x12 := key[5]
+	x13 := key[6]
+	x14 := key[7]
+	x15 := uint32(1797285236)
+
+	for i := 8; i > 0; i -= 2 {
+		// QUAD_ROTATE(A, B, C, 7)
+		x4 ^= bits.RotateLeft32(x8+x12, 7)
+		x9 ^= bits.RotateLeft32(x13+x1, 7)
+		x14 ^= bits.RotateLeft32(x2+x6, 7)
+		x3 ^= bits.RotateLeft32(x7+x11, 7)
+		// QUAD_ROTATE(B, C, D, 9)
+		x8 ^= bits.RotateLeft32(x12+x0, 9)
+		x13 ^= bits.RotateLeft32(x1+x5, 9)
+		x2 ^= bits.RotateLeft32(x6+x10, 9)
+		x7 ^= bits.RotateLeft32(x11+x15, 9)
+		// QUAD_ROTATE(C, D, A, 13)
+		x12 ^= bits.RotateLeft32(x0+x4, 13)
+		x1 ^= bits.RotateLeft32(x5+x9, 13)
+		x6 ^= bits.RotateLeft32(x10+x14, 13)
+		x11 ^= bits.RotateLeft32(x15+x3, 13)
+		// QUAD_ROTATE(D, A, B, 18)
+		x0 ^= bits.RotateLeft32(x4+x8, 18)
+		x5 ^= bits.RotateLeft32(x9+x13, 18)
+		x10 ^= bits.RotateLeft32(x14+x2, 18)
+		x15 ^= bits.RotateLeft32(x3+x7, 18)
+
+		// QUAD_ROTATE(E, F, G, 7)
+		x1 ^= bits.RotateLeft32(x2+x3, 7)
+		x6 ^= bits.RotateLeft32(x7+x4, 7)
+		x11 ^= bits.RotateLeft32(x8+x9, 7)
+		x12 ^= bits.RotateLeft32(x13+x14, 7)
+		// QUAD_ROTATE(F, G, D, 9)
+		x2 ^= bits.RotateLeft32(x3+x0, 9)
+		x7 ^= bits.RotateLeft32(x4+x5, 9)
+		x8 ^= bits.RotateLeft32(x9+x10, 9)
+		x13 ^= bits.RotateLeft32(x14+x15, 9)
+		// QUAD_ROTATE(G, D, E, 13)
+		x3 ^= bits.RotateLeft32(x0+x1, 13)
+		x4 ^= bits.RotateLeft32(x5+x6, 13)
+		x9 ^= bits.RotateLeft32(x10+x11, 13)
+		x14 ^= bits.RotateLeft32(x15+x12, 13)
+		// QUAD_ROTATE(D, E, F, 18)
+		x0 ^= bits.RotateLeft32(x1+x2, 18)
+		x5 ^= bits.RotateLeft32(x6+x7, 18)
+		x10 ^= bits.RotateLeft32(x11+x8, 18)
+		x15 ^= bits.RotateLeft32(x12+x13, 18)
+	}
+
+	x0 += 1634760805
+	x1 += key[0]
+	x2 += key[1]
+	x3 += key[2]
+	x4 += key[3]
+	x5 += 857760878
+	x6 += low
+	x7 += high
+	x10 += 2036477234
+	x11 += key[4]
+	x12 += key[5]
+	x13 += key[6]
+	x14 += key[7]
+	x15 += 1797285236
+
+	// QUAD_OUTPUT(output, A)
+	binary.LittleEndian.PutUint32(output[0:], x4)
+	binary.LittleEndian.PutUint32(output[4:], x9)
+	binary.LittleEndian.PutUint32(output[8:], x14)
+	binary.LittleEndian.PutUint32(output[12:], x3)
+	// QUAD_OUTPUT(output + 4, B)
+	binary.LittleEndian.PutUint32(output[16:], x8)
+	binary.LittleEndian.PutUint32(output[20:], x13)
+	binary.LittleEndian.PutUint32(output[24:], x2)
+	binary.LittleEndian.PutUint32(output[28:], x7)
+	// QUAD_OUTPUT(output + 8, C)
+	binary.LittleEndian.PutUint32(output[32:], x12)
+	binary.LittleEndian.PutUint32(output[36:], x1)
+	binary.LittleEndian.PutUint32(output[40:], x6)
+	binary.LittleEndian.PutUint32(output[44:], x11)
+	// QUAD_OUTPUT(output + 12, D)
+	binary.LittleEndian.PutUint32(output[48:], x0)
+	binary.LittleEndian.PutUint32(output[52:], x5)
+	binary.LittleEndian.PutUint32(output[56:], x10)
+	binary.LittleEndian.PutUint32(output[60:], x15)
+}
+
+// goGuacamole is the Go equivalent of struct guacamole.
+type goGuacamole struct {
+	nonce  uint64
+	index  int
+	keyed  bool
+	key    [8]uint32
+	buffer [BlockSize]byte
+}
+
+func (g *goGuacamole) seed(s uint64) {
+	g.nonce = s
+	g.index = 0
+	if g.keyed {
+		goMashKeyed(&g.key, g.nonce, &g.buffer)
+	} else {
+		goMash(g.nonce, &g.buffer)
+	}
+}
+
+// setKey is the Go equivalent of guacamole_key.
+func (g *goGuacamole) setKey(key *[32]byte) {
+	index := g.index
+	g.keyed = false
+	for i := range g.key {
+		g.key[i] = binary.LittleEndian.Uint32(key[4*i:])
+		g.keyed = g.keyed || g.key[i] != 0
+	}
+	g.seed(g.nonce)
+	g.index = index
+}
+
+func (g *goGuacamole) seek(nonce uint64, index int) {
+	g.seed(nonce)
+	g.index = index
+}
+
+func (g *goGuacamole) tell() (nonce uint64, index int) {
+	return g.nonce, g.index
+}
+
+func (g *goGuacamole) generate(bytes []byte) {
+	for len(bytes) >= BlockSize-g.index {
+		n := copy(bytes, g.buffer[g.index:])
+		bytes = bytes[n:]
+		g.seed(g.nonce + 1)
+	}
+	g.index += copy(bytes, g.buffer[g.index:])
+}
+
+func (g *goGuacamole) uint32() uint32 {
+	var x [4]byte
+	g.generate(x[:])
+	return binary.LittleEndian.Uint32(x[:])
+}
+
+// double was called rk_double in numpy.
+func (g *goGuacamole) double() float64 {
+	// shifts : 67108864 = 0x4000000, 9007199254740992 = 0x20000000000000
+	a := int64(g.uint32() >> 5)
+	b := int64(g.uint32() >> 6)
+	return (float64(a)*67108864.0 + float64(b)) / 9007199254740992.0
+}
+
+func (g *goGuacamole) doubles(dst []float64) {
+	for i := range dst {
+		dst[i] = g.double()
+	}
+}
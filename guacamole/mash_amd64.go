@@ -0,0 +1,17 @@
+//go:build !cgo || purego
+// +build !cgo purego
+
+package guacamole
+
+import (
+	"golang.org/x/sys/cpu"
+)
+
+// goMashAssembly is goMashSSE41 in mash_amd64.s.
+//
+//go:noescape
+func goMashAssembly(number uint64, output *[BlockSize]byte)
+
+func goCPUDetect() bool {
+	return cpu.X86.HasSSE41
+}
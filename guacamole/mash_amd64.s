@@ -0,0 +1,104 @@
+//go:build !cgo || purego
+// +build !cgo purego
+
+// Copyright (c) 2017-2018 Robert Escriva
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+//     * Redistributions of source code must retain the above copyright notice,
+//       this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above copyright
+//       notice, this list of conditions and the following disclaimer in the
+//       documentation and/or other materials provided with the distribution.
+//     * Neither the name of this project nor the names of its contributors
+//       may be used to endorse or promote products derived from this software
+//       without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT OWNER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// This is guacamole_mash_sse41 from guacamole_amd64.s, translated to the Go
+// assembler so that builds without cgo may use it.  The register allocation
+// is the same:  X0-X3 hold rows A-D and X4-X7 are scratch.  The initial values
+// of B and C are kept in X8 and X9 rather than on the stack.
+
+#include "textflag.h"
+
+DATA sse41_D<>+0x00(SB)/4, $1634760805
+DATA sse41_D<>+0x04(SB)/4, $857760878
+DATA sse41_D<>+0x08(SB)/4, $2036477234
+DATA sse41_D<>+0x0c(SB)/4, $1797285236
+GLOBL sse41_D<>(SB), RODATA|NOPTR, $16
+
+// A ^= rotate(B + C, n)
+#define QUAD_ROTATE(A, B, C, T, U, n, m) \
+	MOVO  B, T;  \
+	PADDL C, T;  \
+	MOVO  T, U;  \
+	PSLLL $n, T; \
+	PSRLL $m, U; \
+	PXOR  T, A;  \
+	PXOR  U, A
+
+// func goMashAssembly(number uint64, output *[BlockSize]byte)
+TEXT ·goMashAssembly(SB), NOSPLIT, $0-16
+	MOVQ number+0(FP), AX
+	MOVQ output+8(FP), DI
+
+	// initialize registers
+	PXOR   X0, X0
+	PXOR   X1, X1
+	PXOR   X2, X2
+	PINSRD $2, AX, X2
+	SHRQ   $32, AX
+	PINSRD $3, AX, X1
+	MOVOU  sse41_D<>(SB), X3
+	MOVO   X1, X8
+	MOVO   X2, X9
+
+	// loop
+	MOVQ $4, CX
+
+loop:
+	QUAD_ROTATE(X0, X1, X2, X4, X5, 7, 25)  // A ^= rotate(B + C, 7)
+	QUAD_ROTATE(X1, X2, X3, X6, X7, 9, 23)  // B ^= rotate(C + D, 9)
+	QUAD_ROTATE(X2, X3, X0, X4, X5, 13, 19) // C ^= rotate(D + A, 13)
+	QUAD_ROTATE(X3, X0, X1, X6, X7, 18, 14) // D ^= rotate(A + B, 18)
+
+	PSHUFD $147, X0, X0 // swap A to G
+	PSHUFD $78, X1, X1  // swap B to F
+	PSHUFD $57, X2, X2  // swap C to E
+
+	QUAD_ROTATE(X2, X1, X0, X4, X5, 7, 25)  // E ^= rotate(F + G, 7)
+	QUAD_ROTATE(X1, X0, X3, X6, X7, 9, 23)  // F ^= rotate(G + D, 9)
+	QUAD_ROTATE(X0, X3, X2, X4, X5, 13, 19) // G ^= rotate(D + E, 13)
+	QUAD_ROTATE(X3, X2, X1, X6, X7, 18, 14) // D ^= rotate(E + F, 18)
+
+	PSHUFD $57, X0, X0  // swap G to A
+	PSHUFD $78, X1, X1  // swap F to B
+	PSHUFD $147, X2, X2 // swap E to C
+
+	DECQ CX
+	JNZ  loop
+
+	PADDL X8, X1
+	PADDL X9, X2
+	MOVOU sse41_D<>(SB), X4
+	PADDL X4, X3
+
+	MOVOU X0, 0x00(DI)
+	MOVOU X1, 0x10(DI)
+	MOVOU X2, 0x20(DI)
+	MOVOU X3, 0x30(DI)
+	RET
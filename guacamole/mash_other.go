@@ -0,0 +1,12 @@
+//go:build (cgo && !purego) || !amd64
+// +build cgo,!purego !amd64
+
+package guacamole
+
+func goMashAssembly(number uint64, output *[BlockSize]byte) {
+	goMashGeneric(number, output)
+}
+
+func goCPUDetect() bool {
+	return false
+}
@@ -0,0 +1,37 @@
+package guacamole
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	first8Bytes string  = "\f\xedYO\xb6\x19K\xe6"
+	firstDouble float64 = 0.3099659152297931
+)
+
+func TestGoMashAssembly(t *testing.T) {
+	if !goCPUDetect() {
+		t.Skip("the Go assembly is not built or not supported by this processor")
+	}
+	require := require.New(t)
+	goMaybeEnableAssembly()
+	defer goMaybeEnableAssembly()
+
+	var block [BlockSize]byte
+	goMash(0, &block)
+	require.Equal([]byte(first8Bytes), block[:8])
+
+	numbers := []uint64{0, 1, 2, 63, 64, 1 << 32, 1<<32 - 1, 1<<64 - 1}
+	for i := uint64(0); i < 10000; i++ {
+		numbers = append(numbers, i*0x9e3779b97f4a7c15)
+	}
+	for _, n := range numbers {
+		var expected [BlockSize]byte
+		var actual [BlockSize]byte
+		goMashGeneric(n, &expected)
+		goMash(n, &actual)
+		require.Equal(expected, actual, "number=%d", n)
+	}
+}
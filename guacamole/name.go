@@ -0,0 +1,23 @@
+package guacamole
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// KeyFromName derives a key for NewKeyed from a stable name, such as a test
+// name or a dataset label.  The key is the SHA-256 digest of the name, so it
+// will never change and distinct names select unrelated streams.
+func KeyFromName(name string) [32]byte {
+	return sha256.Sum256([]byte(name))
+}
+
+// SeedFromName derives a seed from a stable name, such as a test name or a
+// dataset label.  It is the first eight bytes of KeyFromName(name), read
+// big-endian.  Seeds derived from distinct names are spread uniformly across
+// the 2^64 seeds, so two of them land within a gigabyte of each other with
+// probability on the order of 2^-40.
+func SeedFromName(name string) uint64 {
+	key := KeyFromName(name)
+	return binary.BigEndian.Uint64(key[:8])
+}
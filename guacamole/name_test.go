@@ -0,0 +1,19 @@
+package guacamole_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"hack.systems/random/guacamole"
+)
+
+func TestNameDerivedSeeds(t *testing.T) {
+	require := require.New(t)
+	require.Equal(uint64(0xe3b0c44298fc1c14), guacamole.SeedFromName(""))
+	require.Equal(uint64(0x1f06ab51895ee53b), guacamole.SeedFromName("TestGuacamole"))
+	key := guacamole.KeyFromName("TestGuacamole")
+	require.Equal("1f06ab51895ee53be9752da8cb56b31a834bfbcce6eb181fca458f7259bdf510", hex.EncodeToString(key[:]))
+	require.NotEqual(guacamole.SeedFromName("TestA"), guacamole.SeedFromName("TestB"))
+}
@@ -0,0 +1,132 @@
+package guacamole
+
+import (
+	"errors"
+	"io"
+)
+
+var (
+	errNegativeOffset = errors.New("guacamole: negative offset")
+	errWhence         = errors.New("guacamole: invalid whence")
+	errUnbounded      = errors.New("guacamole: SeekEnd on an unbounded reader")
+)
+
+// Read fills p with the next len(p) bytes of guacamole.  Read implements
+// io.Reader and never fails; the stream of guacamole is endless.
+func (g *Guacamole) Read(p []byte) (int, error) {
+	if len(p) > 0 {
+		g.Fill(p)
+	}
+	return len(p), nil
+}
+
+// Reader exposes the guacamole stream beginning at a given seed as a
+// file-like object.  It implements io.Reader, io.ReaderAt, and io.Seeker.
+// Offsets are measured in bytes relative to the seed the Reader was created
+// with, so offset 64 of seed i is offset 0 of seed i+1.
+//
+// A Reader created by NewReader is unbounded and rejects io.SeekEnd.  A Reader
+// created by NewSizedReader behaves like a file of the given size:  reads stop
+// with io.EOF at the end and io.SeekEnd is relative to the size.
+//
+// Sequential reads from a Reader are not safe for concurrent use, but ReadAt
+// does not touch the sequential cursor and is safe to call concurrently with
+// other ReadAt calls.
+type Reader struct {
+	g      Guacamole
+	seed   uint64
+	offset int64
+	size   int64
+}
+
+// NewReader returns an unbounded Reader for the stream beginning at seed.
+func NewReader(seed uint64) *Reader {
+	return NewSizedReader(seed, -1)
+}
+
+// NewSizedReader returns a Reader for the first size bytes of the stream
+// beginning at seed.  A negative size makes the Reader unbounded.
+func NewSizedReader(seed uint64, size int64) *Reader {
+	if size < 0 {
+		size = -1
+	}
+	r := &Reader{
+		seed: seed,
+		size: size,
+	}
+	r.g.Seed(seed)
+	return r
+}
+
+// Seed returns the seed from which the Reader's offsets are measured.
+func (r *Reader) Seed() uint64 {
+	return r.seed
+}
+
+// Offset returns the current offset of the sequential cursor.
+func (r *Reader) Offset() int64 {
+	return r.offset
+}
+
+// Size returns the size of the Reader, or -1 if the Reader is unbounded.
+func (r *Reader) Size() int64 {
+	return r.size
+}
+
+// Read implements io.Reader.
+func (r *Reader) Read(p []byte) (int, error) {
+	p, err := r.clip(p, r.offset)
+	if len(p) > 0 {
+		r.g.Fill(p)
+		r.offset += int64(len(p))
+	}
+	return len(p), err
+}
+
+// ReadAt implements io.ReaderAt.  ReadAt does not move the sequential cursor.
+func (r *Reader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errNegativeOffset
+	}
+	p, err := r.clip(p, off)
+	FillAt(r.seed, uint64(off), p)
+	return len(p), err
+}
+
+// Seek implements io.Seeker.  Seeking past the end of a sized Reader is
+// permitted; subsequent reads will return io.EOF.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		if r.size < 0 {
+			return 0, errUnbounded
+		}
+		offset += r.size
+	default:
+		return 0, errWhence
+	}
+	if offset < 0 {
+		return 0, errNegativeOffset
+	}
+	r.offset = offset
+	r.g.Seek(r.seed, uint64(offset))
+	return offset, nil
+}
+
+// clip shortens p so that reading it at off stays within the Reader's size.
+// It returns io.EOF when the read cannot be satisfied in full.
+func (r *Reader) clip(p []byte, off int64) ([]byte, error) {
+	if r.size < 0 {
+		return p, nil
+	}
+	if off >= r.size {
+		return p[:0], io.EOF
+	}
+	if remain := r.size - off; int64(len(p)) > remain {
+		return p[:remain], io.EOF
+	}
+	return p, nil
+}
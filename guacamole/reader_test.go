@@ -0,0 +1,152 @@
+package guacamole_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"hack.systems/random/guacamole"
+)
+
+func TestGuacamoleRead(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+
+	b := make([]byte, 8)
+	n, err := io.ReadFull(g, b)
+	require.NoError(err)
+	require.Equal(8, n)
+	require.Equal([]byte(First8Bytes), b)
+}
+
+func TestReaderSequential(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+	g.Seed(42)
+	expected := g.Bytes(1000)
+
+	r := guacamole.NewReader(42)
+	require.Equal(uint64(42), r.Seed())
+	require.Equal(int64(-1), r.Size())
+	actual := make([]byte, 1000)
+	for off := 0; off < len(actual); off += 37 {
+		end := off + 37
+		if end > len(actual) {
+			end = len(actual)
+		}
+		n, err := r.Read(actual[off:end])
+		require.NoError(err)
+		require.Equal(end-off, n)
+	}
+	require.Equal(expected, actual)
+	require.Equal(int64(1000), r.Offset())
+}
+
+func TestReaderReadAt(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+	g.Seed(7)
+	expected := g.Bytes(4096)
+
+	r := guacamole.NewReader(7)
+	first := make([]byte, 10)
+	_, err := r.Read(first)
+	require.NoError(err)
+
+	for _, off := range []int64{0, 1, 63, 64, 65, 1000, 4000} {
+		b := make([]byte, 96)
+		n, err := r.ReadAt(b, off)
+		require.NoError(err)
+		require.Equal(96, n)
+		require.Equal(expected[off:off+96], b)
+	}
+
+	// the sequential cursor is untouched by ReadAt
+	next := make([]byte, 10)
+	_, err = r.Read(next)
+	require.NoError(err)
+	require.Equal(expected[10:20], next)
+
+	_, err = r.ReadAt(next, -1)
+	require.Error(err)
+}
+
+func TestReaderSeek(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+	g.Seed(0)
+	expected := g.Bytes(1024)
+
+	r := guacamole.NewReader(0)
+	pos, err := r.Seek(100, io.SeekStart)
+	require.NoError(err)
+	require.Equal(int64(100), pos)
+	pos, err = r.Seek(-36, io.SeekCurrent)
+	require.NoError(err)
+	require.Equal(int64(64), pos)
+
+	b := make([]byte, 8)
+	_, err = r.Read(b)
+	require.NoError(err)
+	require.Equal(expected[64:72], b)
+
+	_, err = r.Seek(0, io.SeekEnd)
+	require.Error(err)
+	_, err = r.Seek(-1, io.SeekStart)
+	require.Error(err)
+	_, err = r.Seek(0, 42)
+	require.Error(err)
+}
+
+func TestSizedReader(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+	g.Seed(3)
+	expected := g.Bytes(100)
+
+	r := guacamole.NewSizedReader(3, 100)
+	require.Equal(int64(100), r.Size())
+	actual, err := io.ReadAll(r)
+	require.NoError(err)
+	require.Equal(expected, actual)
+
+	pos, err := r.Seek(-10, io.SeekEnd)
+	require.NoError(err)
+	require.Equal(int64(90), pos)
+	b := make([]byte, 20)
+	n, err := r.Read(b)
+	require.Equal(io.EOF, err)
+	require.Equal(10, n)
+	require.Equal(expected[90:], b[:n])
+
+	n, err = r.ReadAt(b, 95)
+	require.Equal(io.EOF, err)
+	require.Equal(5, n)
+	require.Equal(expected[95:], b[:n])
+
+	n, err = r.ReadAt(b, 100)
+	require.Equal(io.EOF, err)
+	require.Equal(0, n)
+}
+
+func TestReaderServeContent(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+	g.Seed(0)
+	expected := g.Bytes(1 << 16)
+
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		http.ServeContent(w, req, "guacamole", time.Time{}, guacamole.NewSizedReader(0, 1<<16))
+	}
+	req := httptest.NewRequest("GET", "/guacamole", nil)
+	req.Header.Set("Range", "bytes=1000-1999")
+	w := httptest.NewRecorder()
+	handler(w, req)
+	require.Equal(http.StatusPartialContent, w.Code)
+	require.True(bytes.Equal(expected[1000:2000], w.Body.Bytes()))
+}
@@ -0,0 +1,188 @@
+package guacamole
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrSeedRangeOverlap is returned, wrapped with the names involved, when a
+// SeedAllocator is asked for a range that overlaps one it already holds.
+var ErrSeedRangeOverlap = errors.New("guacamole: seed range overlaps another")
+
+var (
+	errSeedRangeEmpty    = errors.New("guacamole: seed range is empty")
+	errSeedRangeTooLarge = errors.New("guacamole: seed range extends past 2^64 bytes")
+)
+
+// SeedRange is a named, contiguous range of bytes of the guacamole stream.  It
+// begins where Seek(Seed, Offset) begins and is Size bytes long.
+type SeedRange struct {
+	Name   string `json:"name"`
+	Seed   uint64 `json:"seed"`
+	Offset uint64 `json:"offset"`
+	Size   uint64 `json:"size"`
+}
+
+// Guacamole returns a new generator positioned at the start of the range.
+func (r SeedRange) Guacamole() *Guacamole {
+	g := New()
+	g.Seek(r.Seed, r.Offset)
+	return g
+}
+
+// start and end are the range's bounds as absolute byte positions.
+func (r SeedRange) start() uint64 {
+	return r.Seed*BlockSize + r.Offset
+}
+
+func (r SeedRange) end() uint64 {
+	return r.start() + r.Size
+}
+
+// SeedAllocator hands out disjoint, named ranges of the guacamole stream so
+// that independently written tests and datasets never draw overlapping bytes.
+// Because seed i+1 begins 64 bytes after seed i, "seed 10 with 1MB of data"
+// overlaps "seed 100"; the allocator tracks ranges in bytes and rejects such
+// collisions.
+//
+// The allocation map may be saved with MarshalJSON and restored with
+// UnmarshalJSON, so that a dataset keeps its seeds from one run to the next.
+// Allocating a name that is already in the map returns its existing range.
+//
+// Positions are tracked as absolute byte offsets into the stream, so a range
+// must lie entirely within the first 2^64 bytes (seeds below 2^58).  A
+// SeedAllocator is safe for concurrent use.
+type SeedAllocator struct {
+	mtx sync.Mutex
+	// sorted by position
+	ranges []SeedRange
+}
+
+// NewSeedAllocator returns an empty SeedAllocator.
+func NewSeedAllocator() *SeedAllocator {
+	return &SeedAllocator{}
+}
+
+// Allocate reserves size bytes for name and returns the seed and offset at
+// which to Seek to draw them.  A new range begins at the first block boundary
+// after every range already reserved, so its offset is zero.  If name is
+// already reserved with the same size, Allocate returns the existing range;
+// with a different size, it returns ErrSeedRangeOverlap.
+func (a *SeedAllocator) Allocate(name string, size uint64) (seed, offset uint64, err error) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if r, ok := a.lookup(name); ok {
+		if r.Size != size {
+			return 0, 0, fmt.Errorf("%w: %q is already reserved with a different size", ErrSeedRangeOverlap, name)
+		}
+		return r.Seed, r.Offset, nil
+	}
+	var next uint64
+	if len(a.ranges) > 0 {
+		end := a.ranges[len(a.ranges)-1].end()
+		next = (end + BlockSize - 1) / BlockSize
+		if next < end/BlockSize {
+			return 0, 0, errSeedRangeTooLarge
+		}
+	}
+	r := SeedRange{Name: name, Seed: next, Size: size}
+	if err := a.insert(r); err != nil {
+		return 0, 0, err
+	}
+	return r.Seed, r.Offset, nil
+}
+
+// Reserve reserves the size bytes that begin at Seek(seed, offset) for name.
+// It returns ErrSeedRangeOverlap if the range overlaps a range that is already
+// reserved, unless the existing range is the same range under the same name.
+func (a *SeedAllocator) Reserve(name string, seed, offset, size uint64) error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	r := SeedRange{Name: name, Seed: seed, Offset: offset, Size: size}
+	if existing, ok := a.lookup(name); ok {
+		if existing.start() == r.start() && existing.Size == r.Size {
+			return nil
+		}
+		return fmt.Errorf("%w: %q is already reserved elsewhere", ErrSeedRangeOverlap, name)
+	}
+	return a.insert(r)
+}
+
+// Lookup returns the range reserved for name.
+func (a *SeedAllocator) Lookup(name string) (SeedRange, bool) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.lookup(name)
+}
+
+// Ranges returns every reserved range in the order they appear in the stream.
+func (a *SeedAllocator) Ranges() []SeedRange {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return append([]SeedRange(nil), a.ranges...)
+}
+
+// MarshalJSON implements json.Marshaler.  The map is written as a list of
+// ranges like {"name":"users","seed":0,"offset":0,"size":1048576}.
+func (a *SeedAllocator) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.Ranges())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.  It replaces the allocator's map
+// with the one recorded by MarshalJSON, and returns an error if the recorded
+// ranges overlap.
+func (a *SeedAllocator) UnmarshalJSON(data []byte) error {
+	var ranges []SeedRange
+	if err := json.Unmarshal(data, &ranges); err != nil {
+		return err
+	}
+	var loaded SeedAllocator
+	for _, r := range ranges {
+		if _, ok := loaded.lookup(r.Name); ok {
+			return fmt.Errorf("%w: %q is reserved twice", ErrSeedRangeOverlap, r.Name)
+		}
+		if err := loaded.insert(r); err != nil {
+			return err
+		}
+	}
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.ranges = loaded.ranges
+	return nil
+}
+
+func (a *SeedAllocator) lookup(name string) (SeedRange, bool) {
+	for _, r := range a.ranges {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return SeedRange{}, false
+}
+
+// insert adds r to the sorted list of ranges after checking that it does not
+// overlap its neighbors.
+func (a *SeedAllocator) insert(r SeedRange) error {
+	if r.Size == 0 {
+		return errSeedRangeEmpty
+	}
+	if r.Seed >= 1<<58 || r.start() < r.Seed*BlockSize || r.end() < r.start() {
+		return errSeedRangeTooLarge
+	}
+	i := sort.Search(len(a.ranges), func(i int) bool {
+		return a.ranges[i].start() >= r.start()
+	})
+	if i > 0 && a.ranges[i-1].end() > r.start() {
+		return fmt.Errorf("%w: %q overlaps %q", ErrSeedRangeOverlap, r.Name, a.ranges[i-1].Name)
+	}
+	if i < len(a.ranges) && r.end() > a.ranges[i].start() {
+		return fmt.Errorf("%w: %q overlaps %q", ErrSeedRangeOverlap, r.Name, a.ranges[i].Name)
+	}
+	a.ranges = append(a.ranges, SeedRange{})
+	copy(a.ranges[i+1:], a.ranges[i:])
+	a.ranges[i] = r
+	return nil
+}
@@ -0,0 +1,118 @@
+package guacamole_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"hack.systems/random/guacamole"
+)
+
+func TestSeedAllocatorAllocate(t *testing.T) {
+	require := require.New(t)
+	a := guacamole.NewSeedAllocator()
+
+	seed, offset, err := a.Allocate("users", 1000)
+	require.NoError(err)
+	require.Equal(uint64(0), seed)
+	require.Equal(uint64(0), offset)
+
+	// the next range begins at the next block boundary
+	seed, offset, err = a.Allocate("orders", 1<<20)
+	require.NoError(err)
+	require.Equal(uint64(16), seed)
+	require.Equal(uint64(0), offset)
+
+	// allocation by name is idempotent
+	seed, offset, err = a.Allocate("users", 1000)
+	require.NoError(err)
+	require.Equal(uint64(0), seed)
+	require.Equal(uint64(0), offset)
+	_, _, err = a.Allocate("users", 1001)
+	require.ErrorIs(err, guacamole.ErrSeedRangeOverlap)
+
+	_, _, err = a.Allocate("empty", 0)
+	require.Error(err)
+
+	r, ok := a.Lookup("orders")
+	require.True(ok)
+	require.Equal(guacamole.SeedRange{Name: "orders", Seed: 16, Size: 1 << 20}, r)
+	_, ok = a.Lookup("missing")
+	require.False(ok)
+
+	// the range's generator draws the bytes Seek would
+	g := guacamole.New()
+	g.Seek(16, 0)
+	require.Equal(g.Bytes(100), r.Guacamole().Bytes(100))
+}
+
+func TestSeedAllocatorReserve(t *testing.T) {
+	require := require.New(t)
+	a := guacamole.NewSeedAllocator()
+
+	// "seed 10 with 1MB of data" overlaps "seed 100"
+	require.NoError(a.Reserve("big", 10, 0, 1<<20))
+	err := a.Reserve("small", 100, 0, 64)
+	require.ErrorIs(err, guacamole.ErrSeedRangeOverlap)
+	require.Contains(err.Error(), `"small" overlaps "big"`)
+
+	// ranges may abut exactly, and offsets are bytes within the stream
+	require.NoError(a.Reserve("before", 9, 0, 64))
+	require.NoError(a.Reserve("after", 10, 1<<20, 1))
+	require.ErrorIs(a.Reserve("overlap", 9, 63, 2), guacamole.ErrSeedRangeOverlap)
+	require.ErrorIs(a.Reserve("overlap", 10+(1<<20)/64, 0, 1), guacamole.ErrSeedRangeOverlap)
+
+	// reserving the same range again is harmless; moving it is not
+	require.NoError(a.Reserve("big", 9, 64, 1<<20))
+	require.ErrorIs(a.Reserve("big", 0, 0, 1), guacamole.ErrSeedRangeOverlap)
+
+	// Allocate continues after the last reserved byte
+	seed, offset, err := a.Allocate("next", 1)
+	require.NoError(err)
+	require.Equal(uint64(10+(1<<20)/64+1), seed)
+	require.Equal(uint64(0), offset)
+
+	names := []string{}
+	for _, r := range a.Ranges() {
+		names = append(names, r.Name)
+	}
+	require.Equal([]string{"before", "big", "after", "next"}, names)
+
+	require.Error(a.Reserve("huge", 1<<58, 0, 1))
+	require.Error(a.Reserve("wraps", 1<<58-1, 0, 65))
+}
+
+func TestSeedAllocatorJSON(t *testing.T) {
+	require := require.New(t)
+	a := guacamole.NewSeedAllocator()
+	_, _, err := a.Allocate("users", 1000)
+	require.NoError(err)
+	require.NoError(a.Reserve("fixtures", 1000, 3, 5))
+
+	data, err := json.Marshal(a)
+	require.NoError(err)
+	require.JSONEq(`[
+		{"name":"users","seed":0,"offset":0,"size":1000},
+		{"name":"fixtures","seed":1000,"offset":3,"size":5}
+	]`, string(data))
+
+	b := guacamole.NewSeedAllocator()
+	require.NoError(json.Unmarshal(data, b))
+	require.Equal(a.Ranges(), b.Ranges())
+	seed, _, err := b.Allocate("users", 1000)
+	require.NoError(err)
+	require.Equal(uint64(0), seed)
+
+	require.ErrorIs(json.Unmarshal([]byte(`[
+		{"name":"a","seed":0,"offset":0,"size":100},
+		{"name":"b","seed":1,"offset":0,"size":100}
+	]`), b), guacamole.ErrSeedRangeOverlap)
+	require.ErrorIs(json.Unmarshal([]byte(`[
+		{"name":"a","seed":0,"offset":0,"size":1},
+		{"name":"a","seed":1,"offset":0,"size":1}
+	]`), b), guacamole.ErrSeedRangeOverlap)
+	require.Error(json.Unmarshal([]byte(`{}`), b))
+	// a failed load leaves the map as it was
+	require.Equal(a.Ranges(), b.Ranges())
+}
@@ -0,0 +1,58 @@
+package guacamole
+
+import (
+	"math/rand"
+	randv2 "math/rand/v2"
+)
+
+var (
+	_ rand.Source64 = (*Source)(nil)
+	_ randv2.Source = (*Source)(nil)
+	_ randv2.Source = (*Guacamole)(nil)
+)
+
+// Source adapts guacamole to math/rand.Source64, so that a *rand.Rand may draw
+// from the seekable, reproducible stream of guacamole.  A *Guacamole already
+// satisfies math/rand/v2.Source and may be passed to rand.New directly.
+//
+// Every call to Int63 or Uint64 consumes exactly 8 bytes of guacamole, so the
+// same seed produces the same values through the Source as through
+// Guacamole.Uint64.
+type Source struct {
+	g *Guacamole
+}
+
+// NewSource returns a Source that draws from a new generator seeded with seed.
+func NewSource(seed uint64) *Source {
+	g := New()
+	g.Seed(seed)
+	return &Source{g: g}
+}
+
+// SourceOf returns a Source that draws from g.  The Source and g share state;
+// reading from one advances the other.
+func SourceOf(g *Guacamole) *Source {
+	return &Source{g: g}
+}
+
+// Guacamole returns the generator underlying the Source, e.g. to Seek it.
+func (s *Source) Guacamole() *Guacamole {
+	return s.g
+}
+
+// Seed implements math/rand.Source.  The int64 seed maps to the guacamole seed
+// with the same two's complement representation; that is, Seed(x) is
+// equivalent to Guacamole.Seed(uint64(x)), and Seed(-1) selects seed 2^64-1.
+func (s *Source) Seed(seed int64) {
+	s.g.Seed(uint64(seed))
+}
+
+// Int63 implements math/rand.Source.  It returns the high 63 bits of Uint64.
+func (s *Source) Int63() int64 {
+	return int64(s.g.Uint64() >> 1)
+}
+
+// Uint64 implements math/rand.Source64 and math/rand/v2.Source.
+func (s *Source) Uint64() uint64 {
+	return s.g.Uint64()
+}
@@ -0,0 +1,65 @@
+package guacamole_test
+
+import (
+	"math/rand"
+	randv2 "math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"hack.systems/random/guacamole"
+)
+
+func TestSourceMatchesGuacamole(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+	g.Seed(1337)
+	s := guacamole.NewSource(1337)
+	for i := 0; i < 1000; i++ {
+		x := g.Uint64()
+		if i%2 == 0 {
+			require.Equal(x, s.Uint64())
+		} else {
+			require.Equal(int64(x>>1), s.Int63())
+		}
+	}
+
+	s.Seed(-1)
+	g.Seed(1<<64 - 1)
+	require.Equal(g.Uint64(), s.Uint64())
+
+	g.Seek(1337, 4096)
+	s.Guacamole().Seek(1337, 4096)
+	require.Equal(g.Uint64(), s.Uint64())
+}
+
+func TestSourceRand(t *testing.T) {
+	require := require.New(t)
+	r1 := rand.New(guacamole.NewSource(42))
+	r2 := rand.New(guacamole.NewSource(42))
+	require.Equal(r1.Perm(100), r2.Perm(100))
+	require.Equal(r1.NormFloat64(), r2.NormFloat64())
+
+	g := guacamole.New()
+	g.Seed(42)
+	r3 := rand.New(guacamole.SourceOf(g))
+	r1.Seed(42)
+	require.Equal(r1.Uint64(), r3.Uint64())
+	require.Equal(r1.Int63(), r3.Int63())
+}
+
+func TestSourceRandV2(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+	g.Seed(42)
+	expected := g.Uint64()
+
+	g.Seed(42)
+	r := randv2.New(g)
+	require.Equal(expected, r.Uint64())
+
+	r1 := randv2.New(guacamole.NewSource(7))
+	r2 := randv2.New(guacamole.NewSource(7))
+	require.Equal(r1.Perm(100), r2.Perm(100))
+	require.Equal(r1.NormFloat64(), r2.NormFloat64())
+}
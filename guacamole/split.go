@@ -0,0 +1,63 @@
+package guacamole
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// jumpBits is the number of low bits of the nonce within one jump.  The 24
+// high bits index the stream.
+const jumpBits = 40
+
+// JumpSize is the number of bytes by which Jump(1) advances a generator:
+// 2^40 blocks, or 64TiB.  Generators that begin a jump apart will not overlap
+// until one of them generates JumpSize bytes.
+const JumpSize = BlockSize << jumpBits
+
+// Jump advances the generator by n*JumpSize bytes, to the same position within
+// the n-th next stream.  The 64-bit nonce holds 2^24 streams, after which Jump
+// wraps around to the first.
+func (g *Guacamole) Jump(n uint64) {
+	nonce, index := g.guac.tell()
+	g.guac.seek(nonce+n<<jumpBits, index)
+}
+
+// Split returns a child generator with a stream of its own.  The child's key
+// is derived from the key of g and the number of children g has already
+// returned, and the child begins at the current position of g; g itself does
+// not move.  Every generator in a tree built by Split, however deep, has a
+// distinct key, so none overlaps another no matter how much each generates.
+// Splitting is deterministic; generators with the same key that split the same
+// number of times return the same children.
+//
+// The marshaled forms of g record its key and how many children it has
+// returned, so a generator restored from a checkpoint splits off new children
+// rather than repeating those returned before the checkpoint.
+//
+// Every child is keyed, so it always draws from the portable implementation
+// rather than the SIMD kernels; where those kernels are available, a child
+// generates bytes several times more slowly than an unkeyed generator.  See
+// NewKeyed.  Where fan-out must be fast and one
+// level deep suffices, copy an unkeyed generator and Jump each copy to a
+// stream of its own instead.
+func (g *Guacamole) Split() *Guacamole {
+	child := *g
+	child.splits = 0
+	child.SetKey(splitKey(&g.key, g.splits))
+	g.splits++
+	return &child
+}
+
+// splitKey returns the key of the i-th child of a generator with the provided
+// key:  the SHA-256 digest of a fixed prefix, the parent key, and i.
+func splitKey(parent *[32]byte, i uint64) [32]byte {
+	var buf [len(splitKeyPrefix) + 32 + 8]byte
+	n := copy(buf[:], splitKeyPrefix)
+	n += copy(buf[n:], parent[:])
+	binary.BigEndian.PutUint64(buf[n:], i)
+	return sha256.Sum256(buf[:])
+}
+
+// splitKeyPrefix separates the keys of children from the keys KeyFromName
+// derives from names.
+const splitKeyPrefix = "guacamole split\x00"
@@ -0,0 +1,161 @@
+package guacamole_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"hack.systems/random/guacamole"
+)
+
+func TestJump(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+	g.Seek(1337, 100)
+	g.Jump(3)
+	seed, offset := g.Position()
+	require.Equal(uint64(1337), seed)
+	require.Equal(100+3*uint64(guacamole.JumpSize), offset)
+
+	expected := guacamole.New()
+	expected.Seek(1337, 100+3*guacamole.JumpSize)
+	require.Equal(expected.Bytes(1000), g.Bytes(1000))
+
+	// jumping zero streams stays put
+	next := expected.Bytes(8)
+	g.Jump(0)
+	require.Equal(next, g.Bytes(8))
+
+	// 2^24 streams wrap around to the first
+	g.Seek(5, 17)
+	first := g.Bytes(8)
+	g.Seek(5, 17)
+	g.Jump(1 << 24)
+	require.Equal(first, g.Bytes(8))
+}
+
+func TestSplit(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+	g.Seek(42, 10)
+	parent := g.Bytes(64)
+	g.Seek(42, 10)
+	children := make([]*guacamole.Guacamole, 8)
+	for i := range children {
+		children[i] = g.Split()
+	}
+	// children begin where the parent is, which does not move
+	for _, child := range children {
+		seed, offset := child.Position()
+		require.Equal(uint64(42), seed)
+		require.Equal(uint64(10), offset)
+	}
+	seed, offset := g.Position()
+	require.Equal(uint64(42), seed)
+	require.Equal(uint64(10), offset)
+	require.Equal(parent, g.Bytes(64))
+
+	// children are independent generators that do not share state
+	a := children[0].Bytes(64)
+	b := children[1].Bytes(64)
+	require.NotEqual(a, b)
+	require.NotEqual(parent, a)
+	require.NotEqual(a, children[0].Bytes(64))
+
+	// splitting is deterministic
+	h := guacamole.New()
+	h.Seek(42, 10)
+	h.Split()
+	require.Equal(b, h.Split().Bytes(64))
+}
+
+// TestSplitTree splits at several levels and checks that no two generators in
+// the tree produce the same 64-byte block, including a child that has split
+// and the parent it was split from.
+func TestSplitTree(t *testing.T) {
+	require := require.New(t)
+	root := guacamole.New()
+	tree := []*guacamole.Guacamole{root}
+	// each level splits every generator so far three times, so parents
+	// keep splitting after their children have
+	for level := 0; level < 3; level++ {
+		for _, g := range tree {
+			for i := 0; i < 3; i++ {
+				tree = append(tree, g.Split())
+			}
+		}
+	}
+	c := root.Split()
+	c.Split()
+	tree = append(tree, c, c.Split().Split())
+	require.Len(tree, 1+3+12+48+2)
+
+	const blocks = 64
+	owner := make(map[string]int)
+	for i, g := range tree {
+		for j := 0; j < blocks; j++ {
+			block := string(g.Bytes(guacamole.BlockSize))
+			other, ok := owner[block]
+			require.False(ok, "generators %d and %d share a block", other, i)
+			owner[block] = i
+		}
+	}
+
+	// the tree is the same when rebuilt
+	first := guacamole.New().Split()
+	first.Seek(0, blocks*guacamole.BlockSize)
+	require.Equal(first.Bytes(64), tree[1].Bytes(64))
+}
+
+func TestSplitKeyed(t *testing.T) {
+	require := require.New(t)
+	var key [32]byte
+	key[0] = 1
+	g := guacamole.NewKeyed(key)
+	h := guacamole.New()
+	// the children of different keys differ, as do the keys' own streams
+	a := g.Split().Bytes(64)
+	b := h.Split().Bytes(64)
+	require.NotEqual(a, b)
+	require.NotEqual(guacamole.NewKeyed(key).Bytes(64), a)
+	require.Equal(a, guacamole.NewKeyed(key).Split().Bytes(64))
+}
+
+// TestSplitAfterUnmarshal checks that a generator restored from any of its
+// marshaled forms splits off new children rather than repeating those the
+// original returned before it was marshaled, at the root and deeper in a tree.
+func TestSplitAfterUnmarshal(t *testing.T) {
+	require := require.New(t)
+	for _, depth := range []int{0, 2} {
+		g := guacamole.New()
+		g.Seek(42, 10)
+		for i := 0; i < depth; i++ {
+			g = g.Split()
+		}
+		var earlier [][]byte
+		for i := 0; i < 4; i++ {
+			earlier = append(earlier, g.Split().Bytes(64))
+		}
+		data, err := g.MarshalBinary()
+		require.NoError(err)
+		text, err := g.MarshalText()
+		require.NoError(err)
+		js, err := g.MarshalJSON()
+		require.NoError(err)
+		next := g.Split().Bytes(64)
+
+		for _, unmarshal := range []func(*guacamole.Guacamole) error{
+			func(r *guacamole.Guacamole) error { return r.UnmarshalBinary(data) },
+			func(r *guacamole.Guacamole) error { return r.UnmarshalText(text) },
+			func(r *guacamole.Guacamole) error { return r.UnmarshalJSON(js) },
+		} {
+			r := guacamole.New()
+			require.NoError(unmarshal(r))
+			child := r.Split().Bytes(64)
+			for i, e := range earlier {
+				require.NotEqual(e, child, "depth=%d child=%d", depth, i)
+			}
+			require.Equal(next, child, "depth=%d", depth)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package guacamole
+
+import (
+	"encoding/binary"
+)
+
+// The functions in this file compute guacamole directly from a position in the
+// stream, without a Guacamole to hold state.  They are pure, do not allocate,
+// and are safe to call concurrently.  They always use the native Go
+// implementation, which avoids the cost of a cgo call per block.
+
+// Block returns the BlockSize bytes of guacamole at the given block index.  It
+// is the same as seeding with index and reading BlockSize bytes.
+func Block(index uint64) [BlockSize]byte {
+	var block [BlockSize]byte
+	goMash(index, &block)
+	return block
+}
+
+// Uint64At returns the uint64 that Uint64 would return after Seek(seed,
+// offset).
+func Uint64At(seed, offset uint64) uint64 {
+	var bytes [8]byte
+	FillAt(seed, offset, bytes[:])
+	return binary.BigEndian.Uint64(bytes[:])
+}
+
+// FillAt fills buf with the bytes that Fill would produce after Seek(seed,
+// offset).
+func FillAt(seed, offset uint64, buf []byte) {
+	nonce := seed + offset/BlockSize
+	index := int(offset % BlockSize)
+	var block [BlockSize]byte
+	for len(buf) > 0 {
+		if index == 0 && len(buf) >= BlockSize {
+			goMash(nonce, (*[BlockSize]byte)(buf))
+			buf = buf[BlockSize:]
+		} else {
+			goMash(nonce, &block)
+			buf = buf[copy(buf, block[index:]):]
+			index = 0
+		}
+		nonce++
+	}
+}
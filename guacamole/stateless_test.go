@@ -0,0 +1,83 @@
+package guacamole_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"hack.systems/random/guacamole"
+)
+
+func TestBlock(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+	for _, index := range []uint64{0, 1, 2, 1 << 32, 1<<64 - 1} {
+		g.Seed(index)
+		block := guacamole.Block(index)
+		require.Equal(g.Bytes(guacamole.BlockSize), block[:])
+	}
+	block := guacamole.Block(0)
+	require.Equal([]byte(First8Bytes), block[:8])
+}
+
+func TestFillAt(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+	for _, seed := range []uint64{0, 1337, 1<<64 - 2} {
+		for _, offset := range []uint64{0, 1, 63, 64, 65, 1000} {
+			for _, sz := range []int{0, 1, 8, 63, 64, 65, 200} {
+				g.Seek(seed, offset)
+				expected := g.Bytes(uint64(sz))
+				actual := make([]byte, sz)
+				guacamole.FillAt(seed, offset, actual)
+				require.Equal(expected, actual, "seed=%d offset=%d size=%d", seed, offset, sz)
+			}
+			g.Seek(seed, offset)
+			require.Equal(g.Uint64(), guacamole.Uint64At(seed, offset))
+		}
+	}
+}
+
+func TestStatelessDoesNotAllocate(t *testing.T) {
+	require := require.New(t)
+	buf := make([]byte, 1000)
+	allocs := testing.AllocsPerRun(100, func() {
+		block := guacamole.Block(42)
+		buf[0] = block[0]
+		_ = guacamole.Uint64At(42, 1001)
+		guacamole.FillAt(42, 13, buf)
+	})
+	require.Equal(float64(0), allocs)
+
+	g := guacamole.New()
+	allocs = testing.AllocsPerRun(100, func() {
+		g.Seek(42, 13)
+	})
+	require.Equal(float64(0), allocs)
+}
+
+func TestStatelessConcurrent(t *testing.T) {
+	require := require.New(t)
+	g := guacamole.New()
+	g.Seed(99)
+	expected := g.Bytes(1 << 16)
+
+	var wg sync.WaitGroup
+	results := make([][]byte, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			buf := make([]byte, len(expected))
+			for off := 0; off < len(buf); off += 4096 {
+				guacamole.FillAt(99, uint64(off), buf[off:off+4096])
+			}
+			results[i] = buf
+		}(i)
+	}
+	wg.Wait()
+	for _, r := range results {
+		require.Equal(expected, r)
+	}
+}
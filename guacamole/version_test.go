@@ -0,0 +1,133 @@
+package guacamole_test
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"hack.systems/random/guacamole"
+)
+
+// versionGolden pins the output of one algorithm version.  Every field is a
+// SHA-256 digest of output drawn from that version; see versionDigests.
+type versionGolden struct {
+	stream   string
+	samplers string
+	zipf     string
+	scramble string
+}
+
+// versionGoldens holds the golden output of every supported version.  A
+// version's entry must never change once it is released.
+var versionGoldens = map[guacamole.Version]versionGolden{
+	guacamole.Version1: {
+		stream:   "b62bb145fb4ada1287641a8401b8fad50d589942cae555d65118c875c712dd18",
+		samplers: "2cfa266b881098e12dc24d1443be1f65a392c79e7600143b9d3060efa6b34a20",
+		zipf:     "ad36df5849dd43cabb3bc8ef7f06b456c7d99306fee16cdbdefb5f7655e4b194",
+		scramble: "d8eff0a6d3d40600720c7c6d4f4f1c28dc385024ae95a8c0e0c1aca235d2d877",
+	},
+}
+
+// versionDigests draws output from every versioned algorithm.  Zipf is drawn
+// only from sets small enough that the C and Go backends agree exactly; larger
+// sets are known to differ between backends in rare draws (see the package
+// documentation) and are covered by TestGoZipfMatchesC instead.
+func versionDigests(v guacamole.Version) versionGolden {
+	digest := func(fill func(put func(uint64))) string {
+		h := sha256.New()
+		var buf [8]byte
+		fill(func(x uint64) {
+			binary.BigEndian.PutUint64(buf[:], x)
+			h.Write(buf[:])
+		})
+		return hex.EncodeToString(h.Sum(nil))
+	}
+	var golden versionGolden
+
+	g := guacamole.NewVersion(v)
+	golden.stream = digest(func(put func(uint64)) {
+		for _, seed := range []uint64{0, 1337, 1<<64 - 1} {
+			g.Seed(seed)
+			for i := 0; i < 1<<17; i++ {
+				put(g.Uint64())
+			}
+		}
+	})
+
+	golden.samplers = digest(func(put func(uint64)) {
+		g.Seed(1337)
+		table := guacamole.NewAliasTable([]float64{1, 2, 3, 4})
+		exact := guacamole.ExactZipfTheta(1000000, 0.99)
+		for i := 0; i < 10000; i++ {
+			put(math.Float64bits(g.Float64()))
+			put(g.Uint64n(1000003))
+			put(math.Float64bits(g.NormFloat64()))
+			put(math.Float64bits(g.ExpFloat64()))
+			put(uint64(g.Weighted(table)))
+			put(g.ExactZipf(exact))
+		}
+	})
+
+	golden.zipf = digest(func(put func(uint64)) {
+		g.Seed(1337)
+		for _, n := range []uint64{10, 1000, 100000, 10000000, 123456789} {
+			for _, theta := range []float64{0, 0.2, 0.5, 0.99} {
+				zp := guacamole.ZipfThetaVersion(n, theta, v)
+				for i := 0; i < 1000; i++ {
+					put(g.Zipf(zp))
+				}
+			}
+		}
+	})
+
+	golden.scramble = digest(func(put func(uint64)) {
+		s := guacamole.NewScramblerVersion(v)
+		for _, bijection := range []uint64{0, 42, 1<<64 - 1} {
+			s.Change(bijection)
+			for x := uint64(0); x < 10000; x++ {
+				put(s.Scramble(x))
+			}
+		}
+	})
+	return golden
+}
+
+func TestVersionGoldens(t *testing.T) {
+	require := require.New(t)
+	for v := guacamole.Version1; v <= guacamole.LatestVersion; v++ {
+		golden, ok := versionGoldens[v]
+		require.True(ok, "version %d has no golden output", v)
+		require.Equal(golden, versionDigests(v), "version %d", v)
+	}
+}
+
+func TestVersionDefaults(t *testing.T) {
+	require := require.New(t)
+	require.Equal(guacamole.Version1, guacamole.New().Version())
+	require.Equal(guacamole.Version1, (&guacamole.Guacamole{}).Version())
+	require.Equal(guacamole.Version1, guacamole.ZipfTheta(10, 0.5).Version())
+	require.Equal(guacamole.Version1, guacamole.NewScrambler().Version())
+	require.Equal(guacamole.Version1, guacamole.NewFastScrambler().Version())
+	require.Equal(guacamole.Version1, (&guacamole.FastScrambler{}).Version())
+	require.Equal(guacamole.Version1, guacamole.NewDomainScrambler(10, 0).Version())
+	require.Equal(guacamole.LatestVersion, guacamole.NewVersion(guacamole.LatestVersion).Version())
+
+	// the unversioned constructors are Version1
+	expected := guacamole.NewVersion(guacamole.Version1)
+	require.Equal(expected.Bytes(1024), guacamole.New().Bytes(1024))
+
+	require.False(guacamole.Version(0).Supported())
+	require.True(guacamole.Version1.Supported())
+	require.False((guacamole.LatestVersion + 1).Supported())
+	require.Panics(func() { guacamole.NewVersion(guacamole.LatestVersion + 1) })
+	require.Panics(func() { guacamole.NewVersion(-1) })
+	require.Panics(func() { guacamole.ZipfThetaVersion(10, 0.5, guacamole.LatestVersion+1) })
+	require.Panics(func() { guacamole.ZipfAlphaVersion(10, 2, guacamole.LatestVersion+1) })
+	require.Panics(func() { guacamole.NewScramblerVersion(guacamole.LatestVersion + 1) })
+	require.Panics(func() { guacamole.NewFastScramblerVersion(guacamole.LatestVersion + 1) })
+	require.Panics(func() { guacamole.NewDomainScramblerVersion(10, 0, guacamole.LatestVersion+1) })
+}
@@ -0,0 +1,181 @@
+package guacamole
+
+import (
+	"math"
+)
+
+// goZipfParams is the Go equivalent of struct guacamole_zipf_params.  The
+// algorithm is from "Quickly Generating Billion-Record Synthetic Databases" by
+// Gray et.al., SIGMOD 1994.
+//
+// Expressions are written with explicit float64 conversions where the C code
+// would not fuse a multiply and an add; this keeps the compiler from emitting
+// FMA instructions that would round differently than guacamole.c does.
+type goZipfParams struct {
+	n     uint64
+	alpha float64
+	theta float64
+	zetan float64
+	zeta2 float64
+	eta   float64
+}
+
+func (p *goZipfParams) initAlpha(n uint64, alpha float64) {
+	p.n = n
+	p.alpha = alpha
+	p.theta = 1. - 1./alpha
+	p.params()
+}
+
+func (p *goZipfParams) initTheta(n uint64, theta float64) {
+	p.n = n
+	p.theta = theta
+	p.alpha = 1. / (1. - theta)
+	p.params()
+}
+
+func (p *goZipfParams) params() {
+	for i := range precomputed {
+		if precomputed[i].n == p.n &&
+			math.Abs((p.theta-precomputed[i].theta)/p.theta) < 0.001 {
+			*p = precomputed[i]
+			return
+		}
+	}
+
+	p.zetan = zipfZeta(p.n, p.theta)
+	// guacamole.c passes the arguments in this order; preserve it so that
+	// both implementations agree.
+	p.zeta2 = zipfZeta(uint64(p.theta), 2)
+	p.eta = (1 - math.Pow(2.0/float64(p.n), 1-p.theta)) /
+		(1 - p.zeta2/p.zetan)
+}
+
+func (p *goZipfParams) dump() (n uint64, alpha, theta, zetan, zeta2, eta float64) {
+	return p.n, p.alpha, p.theta, p.zetan, p.zeta2, p.eta
+}
+
+func (p *goZipfParams) draw(g *goGuacamole) uint64 {
+	u := g.double()
+	uz := u * p.zetan
+	if uz < 1 {
+		return 1
+	}
+	if uz < 1+math.Pow(0.5, p.theta) {
+		return 2
+	}
+	return 1 + uint64(float64(p.n)*math.Pow(float64(p.eta*u)-p.eta+1, p.alpha))
+}
+
+func (p *goZipfParams) fill(g *goGuacamole, dst []uint64) {
+	for i := range dst {
+		dst[i] = p.draw(g)
+	}
+}
+
+// zipfZetaDirect is the number of terms below which zipfZeta sums directly, as
+// it always has, so that every set that is not in the precomputed table draws
+// the same values it always has.  It is the smallest n in the table.  Beyond,
+// the sum is O(n) and would take hours for the largest sets, so it is replaced
+// by zipfHarmonic.
+const zipfZetaDirect = 10000000
+
+func zipfZeta(n uint64, theta float64) float64 {
+	if n >= zipfZetaDirect {
+		return zipfHarmonic(n, theta)
+	}
+	sum := float64(0)
+	for i := uint64(0); i < n; i++ {
+		sum += 1. / math.Pow(float64(i+1), theta)
+	}
+	return sum
+}
+
+// precomputed values generated by zipfgen; they must stay in sync with the
+// table of the same name in guacamole.c.
+var precomputed = []goZipfParams{
+	{10000000, 1.1111111111111112, 0.1, 2.216957624468751e+06, 0, 0.9999990647515522},
+	{10000000, 1.25, 0.2, 497633.2491763715, 0, 0.9999956265517043},
+	{10000000, 1.4285714285714286, 0.3, 113474.56151585997, 0, 0.9999795486963488},
+	{10000000, 1.6666666666666667, 0.4, 26413.75253568109, 0, 0.999904364750021},
+	{10000000, 2, 0.5, 6323.095123940201, 0, 0.9995527864045001},
+	{10000000, 2.5, 0.6, 1575.4407313003119, 0, 0.9979087208948174},
+	{10000000, 3.333333333333333, 0.7, 416.863421780444, 0, 0.9902206723145707},
+	{10000000, 5.000000000000001, 0.8, 121.15678441550145, 0, 0.9542694948072673},
+	{10000000, 10.000000000000002, 0.9, 40.68860959391765, 0, 0.7861530800017623},
+	{10000000, 1, 0, 1e+07, 0, 0.9999998},
+	{10000000, 10, 0.9, 40.68860959391765, 0, 0.7861530800017623},
+	{10000000, 100, 0.99, 18.066242574968303, 0, 0.14294182856772952},
+	{10000000, 1000, 0.999, 16.825835765719074, 0, 0.015306593275091429},
+	{10000000, 10000, 0.9999, 16.70830071644092, 0, 0.0015413058133011415},
+	{100000000, 1.1111111111111112, 0.1, 1.7609923836887527e+07, 0, 0.9999998822591962},
+	{100000000, 1.25, 0.2, 3.139857318025828e+06, 0, 0.9999993068551568},
+	{100000000, 1.4285714285714286, 0.3, 568723.6267934917, 0, 0.9999959194284532},
+	{100000000, 1.6666666666666667, 0.4, 105158.42293108889, 0, 0.9999759775113204},
+	{100000000, 2, 0.5, 19998.53969549304, 0, 0.9998585786437627},
+	{100000000, 2.5, 0.6, 3960.280327627551, 0, 0.9991674467925982},
+	{100000000, 3.333333333333333, 0.7, 834.5170899801662, 0, 0.9950987258106051},
+	{100000000, 5.000000000000001, 0.8, 194.6160470599037, 0, 0.9711460018818557},
+	{100000000, 10.000000000000002, 0.9, 53.665620460177784, 0, 0.8301353535365752},
+	{100000000, 1, 0, 1e+08, 0, 0.99999998},
+	{100000000, 10, 0.9, 53.665620460177784, 0, 0.8301353535365752},
+	{100000000, 100, 0.99, 20.80293049002014, 0, 0.1624508543520593},
+	{100000000, 1000, 0.999, 19.168530903421356, 0, 0.01757132526491123},
+	{100000000, 10000, 0.9999, 19.01486562854262, 0, 0.001771182957221673},
+	{1000000000, 1.1111111111111112, 0.1, 1.3988060077036873e+08, 0, 0.999999985177311},
+	{1000000000, 1.25, 0.2, 1.9811164179768123e+07, 0, 0.9999998901439456},
+	{1000000000, 1.4285714285714286, 0.3, 2.850373832108431e+06, 0, 0.9999991858189369},
+	{1000000000, 1.6666666666666667, 0.4, 418646.6039127252, 0, 0.9999939658236634},
+	{1000000000, 2, 0.5, 63244.092864672115, 0, 0.99995527864045},
+	{1000000000, 2.5, 0.6, 9950.726604378511, 0, 0.999668554598266},
+	{1000000000, 3.333333333333333, 0.7, 1667.8457238955966, 0, 0.9975435439477685},
+	{1000000000, 5.000000000000001, 0.8, 311.0411338557673, 0, 0.9817943579697391},
+	{1000000000, 10.000000000000002, 0.9, 70.00270945700423, 0, 0.8650717152326436},
+	{1000000000, 1, 0, 1e+09, 0, 0.999999998},
+	{1000000000, 10, 0.9, 70.00270945700423, 0, 0.8650717152326436},
+	{1000000000, 100, 0.99, 23.60336410411734, 0, 0.1815158004930929},
+	{1000000000, 1000, 0.999, 21.516626552401423, 0, 0.0198308485156955},
+	{1000000000, 10000, 0.9999, 21.321961748562106, 0, 0.0020010071760672155},
+	{10000000000, 1.1111111111111112, 0.1, 1.1111111105619366e+09, 0, 0.999999998133934},
+	{10000000000, 1.25, 0.2, 1.2499999927108379e+08, 0, 0.9999999825889887},
+	{10000000000, 1.4285714285714286, 0.3, 1.4285713381652365e+07, 0, 0.9999998375495207},
+	{10000000000, 1.6666666666666667, 0.4, 1.6666655319189273e+06, 0, 0.9999984842834335},
+	{10000000000, 2, 0.5, 199998.53965056606, 0, 0.9999858578643762},
+	{10000000000, 2.5, 0.6, 24998.047339044355, 0, 0.9998680492089227},
+	{10000000000, 3.333333333333333, 0.7, 3330.5549449376763, 0, 0.9987688555866551},
+	{10000000000, 5.000000000000001, 0.8, 495.5624615889921, 0, 0.9885130164500296},
+	{10000000000, 10.000000000000002, 0.9, 90.56988598108148, 0, 0.8928226537463706},
+	{10000000000, 1, 0, 1e+10, 0, 0.9999999998},
+	{10000000000, 10, 0.9, 90.56988598108148, 0, 0.8928226537463706},
+	{10000000000, 100, 0.99, 26.46902820178302, 0, 0.20014677547762882},
+	{10000000000, 1000, 0.999, 23.870135124976315, 0, 0.02208517500721141},
+	{10000000000, 10000, 0.9999, 23.62958916236007, 0, 0.0022307784820226884},
+	{100000000000, 1.1111111111111112, 0.1, 8.825869276134706e+09, 0, 0.9999999997650763},
+	{100000000000, 1.25, 0.2, 7.886966799353771e+08, 0, 0.9999999972405407},
+	{100000000000, 1.4285714285714286, 0.3, 7.159817531566392e+07, 0, 0.999999967586868},
+	{100000000000, 1.6666666666666667, 0.4, 6.635118374721357e+06, 0, 0.9999996192692122},
+	{100000000000, 2, 0.5, 632454.0716856867, 0, 0.999995527864045},
+	{100000000000, 2.5, 0.6, 62795.208125225625, 0, 0.9999474694439119},
+	{100000000000, 3.333333333333333, 0.7, 6648.095994617613, 0, 0.99938296613728},
+	{100000000000, 5.000000000000001, 0.8, 788.0090577810104, 0, 0.992752203363223},
+	{100000000000, 10.000000000000002, 0.9, 116.46242715354546, 0, 0.9148660077479215},
+	{100000000000, 1, 0, 1e+11, 0, 0.99999999998},
+	{100000000000, 10, 0.9, 116.46242715354546, 0, 0.9148660077479215},
+	{100000000000, 100, 0.99, 29.40144218795688, 0, 0.21835365769521398},
+	{100000000000, 1000, 0.999, 26.22906909062428, 0, 0.02433431669167374},
+	{100000000000, 10000, 0.9999, 25.937747984051878, 0, 0.002460496887270569},
+	{1000000000000, 1.1111111111111112, 0.1, 7.010637131874431e+10, 0, 0.9999999999704249},
+	{1000000000000, 1.25, 0.2, 4.976339733841656e+09, 0, 0.9999999995626552},
+	{1000000000000, 1.4285714285714286, 0.3, 3.5884091479509526e+08, 0, 0.99999999353273},
+	{1000000000000, 1.6666666666666667, 0.4, 2.6414885528646942e+07, 0, 0.9999999043647501},
+	{1000000000000, 2, 0.5, 1.9999985356904527e+06, 0, 0.9999985857864376},
+	{1000000000000, 2.5, 0.6, 157737.38288988697, 0, 0.9999790872089481},
+	{1000000000000, 3.333333333333333, 0.7, 13267.460663681399, 0, 0.999690750505289},
+	{1000000000000, 5.000000000000001, 0.8, 1251.5056766938928, 0, 0.9954269494807267},
+	{1000000000000, 10.000000000000002, 0.9, 149.05920676466877, 0, 0.9323756662193758},
+	{1000000000000, 1, 0, 1e+12, 0, 0.999999999998},
+	{1000000000000, 10, 0.9, 149.05920676466877, 0, 0.9323756662193758},
+	{1000000000000, 100, 0.99, 32.402164232015615, 0, 0.23614610067579656},
+	{1000000000000, 1000, 0.999, 28.59344125820815, 0, 0.026578285493807807},
+	{1000000000000, 10000, 0.9999, 28.246438229354407, 0, 0.002690162403990004},
+}
@@ -0,0 +1,118 @@
+package guacamole
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestZipfHarmonicMatchesTable checks the Euler-Maclaurin zeta against every
+// entry of the precomputed table.  The table was summed term by term in
+// float64, so its own rounding error grows with n; the tolerance follows it.
+func TestZipfHarmonicMatchesTable(t *testing.T) {
+	require := require.New(t)
+	tolerance := map[uint64]float64{
+		1e7:  1e-12,
+		1e8:  1e-12,
+		1e9:  1e-12,
+		1e10: 1e-11,
+		1e11: 1e-9,
+		1e12: 1e-6,
+	}
+	for _, p := range precomputed {
+		zetan := zipfHarmonic(p.n, p.theta)
+		require.InEpsilon(p.zetan, zetan, tolerance[p.n], "n=%d theta=%g", p.n, p.theta)
+
+		// recompute the rest of the entry from the new zeta
+		var q goZipfParams
+		q.n = p.n
+		q.theta = p.theta
+		q.zetan = zetan
+		// the argument order matches params, and so guacamole.c
+		q.zeta2 = zipfZeta(uint64(q.theta), 2)
+		q.eta = (1 - math.Pow(2.0/float64(q.n), 1-q.theta)) / (1 - q.zeta2/q.zetan)
+		require.InDelta(p.zeta2, q.zeta2, 1e-12, "n=%d theta=%g", p.n, p.theta)
+		require.InDelta(p.eta, q.eta, 1e-12)
+	}
+}
+
+func TestZipfHarmonicMatchesSum(t *testing.T) {
+	require := require.New(t)
+	for _, theta := range []float64{0, 0.1, 0.5, 0.83, 0.99, 0.9999, 1, 1.5, 2} {
+		sum := 0.0
+		for k := uint64(1); k <= 100000; k++ {
+			sum += math.Pow(float64(k), -theta)
+			if k < 100 || k%997 == 0 {
+				require.InEpsilon(sum, zipfHarmonic(k, theta), 1e-13, "k=%d theta=%g", k, theta)
+			}
+		}
+	}
+	require.Equal(0.0, zipfHarmonic(0, 0.5))
+}
+
+func TestZipfZetaCompatible(t *testing.T) {
+	require := require.New(t)
+	// small sets are summed exactly as before
+	sum := 0.0
+	for i := uint64(0); i < 12345; i++ {
+		sum += 1. / math.Pow(float64(i+1), 0.7)
+	}
+	require.Equal(sum, zipfZeta(12345, 0.7))
+	// as are sets below the smallest in the precomputed table
+	for i := uint64(12345); i < 1000000; i++ {
+		sum += 1. / math.Pow(float64(i+1), 0.7)
+	}
+	require.Equal(sum, zipfZeta(1000000, 0.7))
+	require.Equal(uint64(zipfZetaDirect), precomputed[0].n)
+	// the swapped arguments used for zeta2
+	theta := 0.83
+	require.Equal(0.0, zipfZeta(uint64(theta), 2))
+}
+
+// TestZipfVersionMismatch checks that a generator refuses parameters of
+// another version.  No two versions are supported yet, so the parameters are
+// given a version by hand.
+func TestZipfVersionMismatch(t *testing.T) {
+	require := require.New(t)
+	require.NotPanics(func() { checkVersions(0, Version1) })
+	require.Panics(func() { checkVersions(Version1, LatestVersion+1) })
+
+	g := New()
+	zp := ZipfTheta(1000, 0.5)
+	require.NotPanics(func() { g.Zipf(zp) })
+	zp.version = LatestVersion + 1
+	require.Panics(func() { g.Zipf(zp) })
+	require.Panics(func() { g.ZipfFill(zp, make([]uint64, 10)) })
+}
+
+// TestVersionMismatchEveryEntryPoint checks that samplers reached only through
+// other samplers, and every scrambler, refuse a version they do not implement.
+func TestVersionMismatchEveryEntryPoint(t *testing.T) {
+	require := require.New(t)
+	g := New()
+	g.version = LatestVersion + 1
+	require.Panics(func() { g.Normal(NormalMeanStddev(0, 1)) })
+	require.Panics(func() { g.Beta(BetaShape(2, 3)) })
+	require.Panics(func() { g.Int63n(10) })
+	require.Panics(func() { g.Perm(10) })
+	require.Panics(func() { g.NegativeBinomial(NegativeBinomialRP(3, 0.5)) })
+	require.Panics(func() { g.ExactZipf(ExactZipfTheta(1000, 0.5)) })
+
+	s := NewScrambler()
+	s.version = LatestVersion + 1
+	require.Panics(func() { s.Scramble(1) })
+	require.Panics(func() { s.Unscramble(1) })
+	require.Panics(func() { s.ScrambleSlice(make([]uint64, 1), make([]uint64, 1)) })
+
+	f := NewFastScrambler()
+	f.version = LatestVersion + 1
+	require.Panics(func() { f.Change(1) })
+	require.Panics(func() { f.Scramble(1) })
+	require.Panics(func() { f.Unscramble(1) })
+
+	d := NewDomainScrambler(1000, 0)
+	d.version = LatestVersion + 1
+	require.Panics(func() { d.Scramble(1) })
+	require.Panics(func() { d.Unscramble(1) })
+}
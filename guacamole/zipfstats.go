@@ -0,0 +1,123 @@
+package guacamole
+
+import (
+	"math"
+)
+
+// PMF, CDF and Quantile describe the Zipf law that ZipfParams is built from,
+// in which element k is chosen with probability k^-theta / zetan.  TopKMass
+// instead describes the output of Guacamole.Zipf, which is what a cache in
+// front of a Zipf workload will actually see; see the BUG on ZipfParams for
+// how the two differ.
+
+// PMF returns the probability of element k, k^-theta / zetan, or zero if k is
+// not in [1, N].
+func (z *ZipfParams) PMF(k uint64) float64 {
+	n, _, theta, zetan, _, _ := z.gzp.dump()
+	if k < 1 || k > n {
+		return 0
+	}
+	return math.Pow(float64(k), -theta) / zetan
+}
+
+// CDF returns the probability of an element in [1, k].  Large k use an
+// Euler-Maclaurin approximation with a relative error below 1e-13.
+func (z *ZipfParams) CDF(k uint64) float64 {
+	n, _, theta, zetan, _, _ := z.gzp.dump()
+	if k < 1 {
+		return 0
+	}
+	if k >= n {
+		return 1
+	}
+	return math.Min(zipfHarmonic(k, theta)/zetan, 1)
+}
+
+// Quantile returns the smallest k for which CDF(k) >= p.  It panics if p is
+// NaN.
+func (z *ZipfParams) Quantile(p float64) uint64 {
+	if math.IsNaN(p) {
+		panic("guacamole: invalid argument to Quantile")
+	}
+	lo, hi := uint64(1), z.N()
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if z.CDF(mid) >= p {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo
+}
+
+// TopKMass returns the fraction of the values returned by Guacamole.Zipf that
+// are expected to be one of the k most popular elements, [1, k].
+func (z *ZipfParams) TopKMass(k uint64) float64 {
+	n, alpha, theta, zetan, _, eta := z.gzp.dump()
+	if k < 1 {
+		return 0
+	}
+	if k >= n {
+		return 1
+	}
+	// Zipf returns 1 for u in [0, 1/zetan), 2 for u in [1/zetan, f2), and
+	// 1 + floor(n*(eta*u - eta + 1)^alpha) otherwise; that last expression is
+	// at most k for u < t.
+	f1 := math.Min(1/zetan, 1)
+	f2 := math.Min((1+math.Pow(0.5, theta))/zetan, 1)
+	t := 1 - (1-math.Pow(float64(k)/float64(n), 1/alpha))/eta
+	mass := f2
+	if k == 1 {
+		mass = f1
+	}
+	if t > f2 {
+		mass += math.Min(t, 1) - f2
+	}
+	return mass
+}
+
+// zipfHarmonicDirect is the number of terms zipfHarmonic sums directly before
+// switching to Euler-Maclaurin.
+const zipfHarmonicDirect = 32
+
+// zipfHarmonic returns the generalized harmonic number H(k, theta), the sum of
+// i^-theta for i in [1, k], in O(1) time.
+//
+// The first terms are summed directly; the rest are approximated with the
+// Euler-Maclaurin formula through the B8 term.  With 32 terms summed directly,
+// the remainder is bounded by about 1e-14 of the result for theta in [0, 2].
+// It must stay in sync with zipf_harmonic in guacamole.c.
+func zipfHarmonic(k uint64, theta float64) float64 {
+	sum := 0.0
+	for i := uint64(1); i <= k && i < zipfHarmonicDirect; i++ {
+		sum += math.Pow(float64(i), -theta)
+	}
+	if k < zipfHarmonicDirect {
+		return sum
+	}
+	a := float64(zipfHarmonicDirect)
+	b := float64(k)
+	// the integral of x^-theta from a to b, written to stay accurate as theta
+	// approaches 1
+	logBA := math.Log(b / a)
+	sum += math.Pow(a, 1-theta) * logBA * zipfHelper2((1-theta)*logBA)
+	// the endpoint correction
+	fa := math.Pow(a, -theta)
+	fb := math.Pow(b, -theta)
+	sum += (fa + fb) / 2
+	// B2/2! f'(x) + B4/4! f'''(x) + ..., where the derivatives of x^-theta
+	// are products of rising factorials of theta and x^(-theta-n)
+	coefficients := [...]float64{1.0 / 12, -1.0 / 720, 1.0 / 30240, -1.0 / 1209600}
+	rising := -theta
+	da := fa / a
+	db := fb / b
+	for j, c := range coefficients {
+		sum += c * rising * (db - da)
+		d := float64(2*j + 1)
+		rising *= (theta + d) * (theta + d + 1)
+		da /= a * a
+		db /= b * b
+	}
+	return sum
+}
@@ -0,0 +1,111 @@
+package guacamole_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"hack.systems/random/guacamole"
+)
+
+var zipfStatsParameters = []struct {
+	n     uint64
+	theta float64
+}{
+	{10, 0.5},
+	{100, 0.1},
+	{1000, 0.99},
+	{12345, 0.7},
+	{50000, 0.9999},
+	{50000, 0},
+}
+
+// TestZipfCDFBruteForce checks PMF and the Euler-Maclaurin CDF against sums
+// of the PMF for every k.
+func TestZipfCDFBruteForce(t *testing.T) {
+	require := require.New(t)
+	for _, p := range zipfStatsParameters {
+		zp := guacamole.ZipfTheta(p.n, p.theta)
+		_, _, _, zetan, _, _ := zp.Dump()
+		require.Equal(0.0, zp.PMF(0))
+		require.Equal(0.0, zp.PMF(p.n+1))
+		require.Equal(0.0, zp.CDF(0))
+		require.Equal(1.0, zp.CDF(p.n))
+		sum := 0.0
+		for k := uint64(1); k < p.n; k++ {
+			pmf := zp.PMF(k)
+			require.InEpsilon(math.Pow(float64(k), -p.theta)/zetan, pmf, 1e-15)
+			sum += pmf
+			require.InEpsilon(sum, zp.CDF(k), 1e-12, "n=%d theta=%g k=%d", p.n, p.theta, k)
+		}
+	}
+}
+
+func TestZipfCDFLarge(t *testing.T) {
+	require := require.New(t)
+	// These are all entries of the precomputed table.  The table was summed
+	// term by term in float64, so its error grows with n to about 1e-7 for
+	// n = 1e12; the Euler-Maclaurin sum is accurate to a few ulps.
+	for _, theta := range []float64{0.1, 0.5, 0.9, 0.99, 0.9999} {
+		for _, n := range []uint64{1e7, 1e8, 1e9, 1e10, 1e11, 1e12} {
+			zp := guacamole.ZipfTheta(n, theta)
+			require.InDelta(1, zp.CDF(n-1)+zp.PMF(n), 1e-6, "n=%d theta=%g", n, theta)
+			require.True(zp.CDF(n/2) < zp.CDF(n-1))
+		}
+	}
+}
+
+func TestZipfQuantile(t *testing.T) {
+	require := require.New(t)
+	for _, p := range zipfStatsParameters {
+		zp := guacamole.ZipfTheta(p.n, p.theta)
+		require.Equal(uint64(1), zp.Quantile(0))
+		require.Equal(uint64(1), zp.Quantile(-1))
+		require.Equal(p.n, zp.Quantile(1))
+		for _, q := range []float64{0.01, 0.1, 0.5, 0.9, 0.99} {
+			k := zp.Quantile(q)
+			require.True(zp.CDF(k) >= q)
+			if k > 1 {
+				require.True(zp.CDF(k-1) < q)
+			}
+		}
+	}
+	require.Panics(func() { guacamole.ZipfTheta(10, 0.5).Quantile(math.NaN()) })
+}
+
+// TestZipfTopKMass checks TopKMass against the observed output of Zipf.
+func TestZipfTopKMass(t *testing.T) {
+	require := require.New(t)
+	const draws = 1000000
+	for _, zp := range []*guacamole.ZipfParams{
+		guacamole.ZipfTheta(1000, 0.99),
+		guacamole.ZipfTheta(12345, 0.5),
+		guacamole.ZipfAlpha(1000, 2),
+	} {
+		n := zp.N()
+		g := guacamole.New()
+		counts := make([]int, n+2)
+		for i := 0; i < draws; i++ {
+			counts[g.Zipf(zp)]++
+		}
+		cumulative := 0
+		for k := uint64(1); k <= n; k++ {
+			cumulative += counts[k]
+			if k == 1 || k == 2 || k == 10 || k == n/10 || k == n/2 || k == n-n/100 {
+				mass := zp.TopKMass(k)
+				sigma := math.Sqrt(mass * (1 - mass) * draws)
+				require.InDelta(mass*draws, float64(cumulative), 5*sigma+1, "n=%d k=%d", n, k)
+			}
+		}
+		require.Equal(1.0, zp.TopKMass(n))
+		require.Equal(0.0, zp.TopKMass(0))
+	}
+}
+
+func BenchmarkZipfCDF(b *testing.B) {
+	zp := guacamole.ZipfTheta(1e12, 0.99)
+	for n := 0; n < b.N; n++ {
+		zp.CDF(uint64(n) + 1e6)
+	}
+}